@@ -0,0 +1,123 @@
+package archive
+
+import (
+	"archive/zip"
+	"errors"
+	"io"
+	"path"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 题目包中单个文本文件（题目信息、题面、代码）的最大长度
+const maxTextFile = 16 << 20
+
+// Package 解析后的题目包
+type Package struct {
+	Problem
+	Content string          // 题面
+	Tests   []*TestCaseFile // 按顺序排列的测试用例
+	dir     string
+	files   map[string]*zip.File
+}
+
+// Open 解析题目包，支持本项目导出的problem.yaml格式和Codeforces Polygon的完整题目包(problem.xml)，
+// 题目包可以在压缩包的根目录或其中的一个目录中
+func Open(zr *zip.Reader) (*Package, error) {
+	pkg := &Package{files: make(map[string]*zip.File)}
+	var meta *zip.File
+	for _, f := range zr.File {
+		pkg.files[f.Name] = f
+		base := path.Base(f.Name)
+		if f.FileInfo().IsDir() || ignored(f.Name) || (base != ProblemFile && base != polygonFile) {
+			continue
+		}
+		// 选择层级最浅的题目信息文件，同一层级时优先problem.yaml
+		if meta == nil || depth(f.Name) < depth(meta.Name) ||
+			(depth(f.Name) == depth(meta.Name) && base == ProblemFile) {
+			meta = f
+		}
+	}
+	if meta == nil {
+		return nil, errors.New("压缩包中没有" + ProblemFile + "或" + polygonFile)
+	}
+	if i := strings.LastIndex(meta.Name, "/"); i >= 0 {
+		pkg.dir = meta.Name[:i+1]
+	}
+	data, err := readZipFile(meta)
+	if err != nil {
+		return nil, err
+	}
+	if path.Base(meta.Name) == polygonFile {
+		err = pkg.parsePolygon(data)
+	} else {
+		err = pkg.parseYAML(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return pkg, nil
+}
+
+// parseYAML 解析problem.yaml格式的题目包
+func (pkg *Package) parseYAML(data []byte) error {
+	if err := yaml.Unmarshal(data, &pkg.Problem); err != nil {
+		return errors.New(ProblemFile + "格式错误:" + err.Error())
+	}
+	if f := pkg.file(StatementFile); f != nil {
+		content, err := readZipFile(f)
+		if err != nil {
+			return err
+		}
+		pkg.Content = string(content)
+	}
+	files := make([]*zip.File, 0)
+	for _, f := range pkg.files {
+		files = append(files, f)
+	}
+	tests, err := TestCases(files, pkg.dir+TestsDir)
+	if err != nil {
+		return err
+	}
+	if err := MarkSamples(tests, pkg.Samples); err != nil {
+		return err
+	}
+	pkg.Tests = tests
+	for _, prog := range []*Program{pkg.Checker, pkg.Interactor} {
+		if prog != nil && pkg.file(prog.Source) == nil {
+			return errors.New("缺少代码文件" + prog.Source)
+		}
+	}
+	return nil
+}
+
+// Source 读取评测程序或交互程序的代码
+func (pkg *Package) Source(prog *Program) ([]byte, error) {
+	f := pkg.file(prog.Source)
+	if f == nil {
+		return nil, errors.New("缺少代码文件" + prog.Source)
+	}
+	return readZipFile(f)
+}
+
+// file 题目包中的文件，name为相对题目包目录的路径
+func (pkg *Package) file(name string) *zip.File {
+	return pkg.files[pkg.dir+strings.TrimPrefix(path.Clean("/"+name), "/")]
+}
+
+func depth(name string) int {
+	return strings.Count(name, "/")
+}
+
+func readZipFile(f *zip.File) ([]byte, error) {
+	if f.UncompressedSize64 > maxTextFile {
+		return nil, errors.New(f.Name + "过大")
+	}
+	r, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(io.LimitReader(r, maxTextFile))
+}
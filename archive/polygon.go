@@ -0,0 +1,179 @@
+package archive
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"gin_gorm_oj/judge"
+	"strconv"
+	"strings"
+)
+
+// Polygon题目包的题目信息文件
+const polygonFile = "problem.xml"
+
+// polygonProblem problem.xml中用到的部分
+type polygonProblem struct {
+	ShortName string `xml:"short-name,attr"`
+	Names     []struct {
+		Language string `xml:"language,attr"`
+		Value    string `xml:"value,attr"`
+	} `xml:"names>name"`
+	Statements []struct {
+		Language string `xml:"language,attr"`
+		Path     string `xml:"path,attr"`
+		Type     string `xml:"type,attr"`
+	} `xml:"statements>statement"`
+	Testsets []struct {
+		Name          string `xml:"name,attr"`
+		TimeLimit     int    `xml:"time-limit"`   // ms
+		MemoryLimit   int64  `xml:"memory-limit"` // 字节
+		TestCount     int    `xml:"test-count"`
+		InputPattern  string `xml:"input-path-pattern"`
+		AnswerPattern string `xml:"answer-path-pattern"`
+		Tests         []struct {
+			Sample bool `xml:"sample,attr"`
+		} `xml:"tests>test"`
+	} `xml:"judging>testset"`
+	Checker *struct {
+		Name string `xml:"name,attr"`
+	} `xml:"assets>checker"`
+	Interactor *struct{} `xml:"assets>interactor"`
+	Tags       []struct {
+		Value string `xml:"value,attr"`
+	} `xml:"tags>tag"`
+}
+
+// Polygon的标准评测程序对应的比较方式
+var polygonCheckers = map[string]Problem{
+	"std::wcmp.cpp":   {CompareMode: judge.CompareToken},
+	"std::ncmp.cpp":   {CompareMode: judge.CompareToken},
+	"std::icmp.cpp":   {CompareMode: judge.CompareToken},
+	"std::uncmp.cpp":  {CompareMode: judge.CompareToken},
+	"std::hcmp.cpp":   {CompareMode: judge.CompareToken},
+	"std::lcmp.cpp":   {CompareMode: judge.CompareToken},
+	"std::fcmp.cpp":   {CompareMode: judge.CompareTrailing},
+	"std::yesno.cpp":  {CompareMode: judge.CompareNoCase},
+	"std::nyesno.cpp": {CompareMode: judge.CompareNoCase},
+	"std::rcmp.cpp":   {CompareMode: judge.CompareFloat, AbsEpsilon: 1.5e-6},
+	"std::rcmp4.cpp":  {CompareMode: judge.CompareFloat, AbsEpsilon: 1e-4, RelEpsilon: 1e-4},
+	"std::rcmp6.cpp":  {CompareMode: judge.CompareFloat, AbsEpsilon: 1e-6, RelEpsilon: 1e-6},
+	"std::rcmp9.cpp":  {CompareMode: judge.CompareFloat, AbsEpsilon: 1e-9, RelEpsilon: 1e-9},
+	"std::dcmp.cpp":   {CompareMode: judge.CompareFloat, AbsEpsilon: 1e-6, RelEpsilon: 1e-6},
+}
+
+// 题面使用的语言，依次选择
+var polygonLanguages = []string{"chinese", "english"}
+
+// parsePolygon 解析Polygon的完整题目包（包含生成的测试数据）
+// 标准评测程序转换为对应的比较方式，不支持自定义评测程序和交互题
+func (pkg *Package) parsePolygon(data []byte) error {
+	p := new(polygonProblem)
+	if err := xml.Unmarshal(data, p); err != nil {
+		return errors.New(polygonFile + "格式错误:" + err.Error())
+	}
+	if p.Interactor != nil {
+		return errors.New("不支持导入Polygon交互题")
+	}
+	if p.Checker != nil {
+		cmp, ok := polygonCheckers[p.Checker.Name]
+		if !ok {
+			return errors.New("不支持导入Polygon评测程序" + p.Checker.Name + "，请导入后上传评测程序")
+		}
+		pkg.CompareMode, pkg.AbsEpsilon, pkg.RelEpsilon = cmp.CompareMode, cmp.AbsEpsilon, cmp.RelEpsilon
+	}
+
+	lang := ""
+	for _, l := range polygonLanguages {
+		for _, n := range p.Names {
+			if lang == "" && n.Language == l {
+				lang = l
+			}
+		}
+	}
+	if lang == "" && len(p.Names) > 0 {
+		lang = p.Names[0].Language
+	}
+	pkg.Title = p.ShortName
+	for _, n := range p.Names {
+		if n.Language == lang {
+			pkg.Title = n.Value
+		}
+	}
+	content, err := pkg.polygonStatement(p, lang)
+	if err != nil {
+		return err
+	}
+	pkg.Content = content
+	for _, t := range p.Tags {
+		pkg.Categories = append(pkg.Categories, t.Value)
+	}
+
+	if len(p.Testsets) == 0 {
+		return errors.New(polygonFile + "中没有测试数据")
+	}
+	ts := p.Testsets[0]
+	for _, t := range p.Testsets {
+		if t.Name == "tests" {
+			ts = t
+		}
+	}
+	pkg.MaxRuntime = ts.TimeLimit
+	pkg.MaxMem = int(ts.MemoryLimit / 1024)
+	for i := 1; i <= ts.TestCount; i++ {
+		in := pkg.file(fmt.Sprintf(ts.InputPattern, i))
+		ans := pkg.file(fmt.Sprintf(ts.AnswerPattern, i))
+		if in == nil || ans == nil {
+			return errors.New("缺少第" + strconv.Itoa(i) + "个测试用例的数据，请使用包含测试数据的完整题目包(full package)")
+		}
+		tc := &TestCaseFile{Name: strconv.Itoa(i), Input: in, Output: ans}
+		if i <= len(ts.Tests) && ts.Tests[i-1].Sample {
+			tc.Sample = true
+			pkg.Samples = append(pkg.Samples, tc.Name)
+		}
+		pkg.Tests = append(pkg.Tests, tc)
+	}
+	if len(pkg.Tests) == 0 {
+		return errors.New(polygonFile + "中没有测试数据")
+	}
+	return nil
+}
+
+// polygonStatement 题面，优先使用statement-sections中的各个部分，否则使用完整的题面文件
+func (pkg *Package) polygonStatement(p *polygonProblem, lang string) (string, error) {
+	sections := []struct{ file, title string }{
+		{"legend.tex", ""},
+		{"input.tex", "## 输入格式"},
+		{"output.tex", "## 输出格式"},
+		{"notes.tex", "## 说明"},
+	}
+	parts := make([]string, 0, len(sections))
+	for _, s := range sections {
+		f := pkg.file("statement-sections/" + lang + "/" + s.file)
+		if f == nil {
+			continue
+		}
+		data, err := readZipFile(f)
+		if err != nil {
+			return "", err
+		}
+		text := strings.TrimSpace(string(data))
+		if s.title != "" {
+			text = s.title + "\n\n" + text
+		}
+		parts = append(parts, text)
+	}
+	if len(parts) > 0 {
+		return strings.Join(parts, "\n\n") + "\n", nil
+	}
+	for _, s := range p.Statements {
+		if s.Language != lang || (s.Type != "application/x-tex" && s.Type != "text/html") {
+			continue
+		}
+		if f := pkg.file(s.Path); f != nil {
+			data, err := readZipFile(f)
+			return string(data), err
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,52 @@
+package archive
+
+import "gopkg.in/yaml.v3"
+
+// 题目包的目录结构：
+//
+//	problem.yaml        题目信息，见Problem
+//	statement.md        题面
+//	tests/1.in 1.out    测试用例，标准输出也可以是.ans
+//	checker/main.cpp    评测程序代码（可选）
+//	interactor/main.cpp 交互程序代码（可选）
+const (
+	ProblemFile   = "problem.yaml"
+	StatementFile = "statement.md"
+	TestsDir      = "tests/"
+)
+
+// Problem 题目包中problem.yaml的内容，时间单位为ms，内存和输出限制单位为KB
+type Problem struct {
+	Title          string          `yaml:"title"`
+	MaxRuntime     int             `yaml:"max_runtime"`
+	MaxMem         int             `yaml:"max_mem"`
+	MaxOutput      int             `yaml:"max_output,omitempty"`
+	CompareMode    string          `yaml:"compare_mode,omitempty"`
+	AbsEpsilon     float64         `yaml:"abs_epsilon,omitempty"`
+	RelEpsilon     float64         `yaml:"rel_epsilon,omitempty"`
+	Categories     []string        `yaml:"categories,omitempty"` // 分类名称
+	Samples        []string        `yaml:"samples,omitempty"`    // 作为样例的测试用例名称，如"1"
+	LanguageLimits []LanguageLimit `yaml:"language_limits,omitempty"`
+	Checker        *Program        `yaml:"checker,omitempty"`
+	Interactor     *Program        `yaml:"interactor,omitempty"`
+}
+
+// LanguageLimit 题目在某种编程语言下的限制规则
+type LanguageLimit struct {
+	Language   string  `yaml:"language"`
+	TimeFactor float64 `yaml:"time_factor,omitempty"`
+	MemFactor  float64 `yaml:"mem_factor,omitempty"`
+	MaxRuntime int     `yaml:"max_runtime,omitempty"`
+	MaxMem     int     `yaml:"max_mem,omitempty"`
+}
+
+// Program 评测程序或交互程序，Source为代码在题目包中的路径
+type Program struct {
+	Language string `yaml:"language"`
+	Source   string `yaml:"source"`
+}
+
+// Marshal 生成problem.yaml
+func (p *Problem) Marshal() ([]byte, error) {
+	return yaml.Marshal(p)
+}
@@ -0,0 +1,107 @@
+package archive
+
+import (
+	"archive/zip"
+	"errors"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 测试用例文件的扩展名，标准输出可以是.out或.ans
+const (
+	inputExt  = ".in"
+	outputExt = ".out"
+	answerExt = ".ans"
+)
+
+// TestCaseFile 压缩包中成对的输入和标准输出文件
+type TestCaseFile struct {
+	Name   string // 去掉扩展名的文件路径，如"1"、"data/2"
+	Input  *zip.File
+	Output *zip.File
+	Sample bool // 是否为样例
+}
+
+// MarkSamples 将名称在names中的测试用例标记为样例，names中有不存在的测试用例时返回错误
+func MarkSamples(tcs []*TestCaseFile, names []string) error {
+	for _, name := range names {
+		found := false
+		for _, tc := range tcs {
+			if tc.Name == strings.TrimSpace(name) {
+				tc.Sample = true
+				found = true
+			}
+		}
+		if !found {
+			return errors.New("样例 " + name + " 不存在")
+		}
+	}
+	return nil
+}
+
+// TestCases 按照文件名配对压缩包dir目录下的测试用例，如1.in和1.out（或1.ans），
+// 按照名称排序（数字按数值大小），忽略其他文件，缺少输入或标准输出时返回错误
+func TestCases(files []*zip.File, dir string) ([]*TestCaseFile, error) {
+	cases := make(map[string]*TestCaseFile)
+	for _, f := range files {
+		if f.FileInfo().IsDir() || ignored(f.Name) {
+			continue
+		}
+		name := strings.TrimPrefix(f.Name, dir)
+		if dir != "" && name == f.Name {
+			continue
+		}
+		ext := path.Ext(name)
+		if ext != inputExt && ext != outputExt && ext != answerExt {
+			continue
+		}
+		base := strings.TrimSuffix(name, ext)
+		tc, ok := cases[base]
+		if !ok {
+			tc = &TestCaseFile{Name: base}
+			cases[base] = tc
+		}
+		if ext == inputExt {
+			tc.Input = f
+			continue
+		}
+		if tc.Output != nil {
+			return nil, errors.New("测试用例 " + base + " 有多个标准输出文件")
+		}
+		tc.Output = f
+	}
+	if len(cases) == 0 {
+		return nil, errors.New("压缩包中没有测试用例")
+	}
+	res := make([]*TestCaseFile, 0, len(cases))
+	for _, tc := range cases {
+		if tc.Input == nil {
+			return nil, errors.New("测试用例 " + tc.Name + " 缺少输入文件(.in)")
+		}
+		if tc.Output == nil {
+			return nil, errors.New("测试用例 " + tc.Name + " 缺少标准输出文件(.out或.ans)")
+		}
+		res = append(res, tc)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return lessName(res[i].Name, res[j].Name)
+	})
+	return res, nil
+}
+
+// ignored 压缩软件生成的文件，如macOS的__MACOSX目录和._开头的文件
+func ignored(name string) bool {
+	return strings.HasPrefix(name, "__MACOSX/") || strings.HasPrefix(path.Base(name), ".")
+}
+
+// lessName 文件名都是数字时按数值大小比较，否则按字符串比较
+func lessName(a, b string) bool {
+	x, errX := strconv.Atoi(path.Base(a))
+	y, errY := strconv.Atoi(path.Base(b))
+	if errX == nil && errY == nil && path.Dir(a) == path.Dir(b) {
+		return x < y
+	}
+	return a < b
+}
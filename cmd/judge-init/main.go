@@ -0,0 +1,9 @@
+package main
+
+import "gin_gorm_oj/judge"
+
+// 用户程序的启动进程，创建子进程设置资源限制后执行用户程序，并报告其运行结果
+// go build -o judge-init ./cmd/judge-init
+func main() {
+	judge.Init()
+}
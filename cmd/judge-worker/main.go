@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"gin_gorm_oj/define"
+	"gin_gorm_oj/judge"
+	"gin_gorm_oj/queue"
+	"gin_gorm_oj/storage"
+	"gin_gorm_oj/worker"
+	"log"
+	"os"
+	"os/signal"
+	"runtime"
+	"strings"
+	"syscall"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// 独立部署的判题节点，从redis中取出任务判断后返回结果，不访问数据库，可以在多台机器上运行
+// go build -o judge-worker ./cmd/judge-worker
+// 测试数据需要从对象存储(-storage s3)或共享目录读取
+func main() {
+	redisAddr := flag.String("redis", define.RedisAddr, "redis地址")
+	redisPassword := flag.String("redis-password", "", "redis密码")
+	n := flag.Int("n", runtime.NumCPU(), "同时处理的任务数")
+	languages := flag.String("languages", "", "处理的编程语言，逗号分隔，默认为本机安装了编译器或解释器的编程语言")
+	id := flag.String("id", "", "判题节点的标识，默认为主机名-进程号")
+	flag.StringVar(&define.JudgeInitPath, "init", define.JudgeInitPath, "judge-init的路径")
+	flag.StringVar(&define.JudgeCgroupRoot, "cgroup", define.JudgeCgroupRoot, "cgroup v2的目录")
+	flag.BoolVar(&define.JudgeSandbox, "sandbox", define.JudgeSandbox, "是否在隔离环境中运行用户程序")
+	flag.StringVar(&define.JudgeCompileCacheDir, "compile-cache-dir", define.JudgeCompileCacheDir, "编译器的缓存目录")
+	flag.IntVar(&define.JudgeMaxProcs, "max-procs", define.JudgeMaxProcs, "用户程序的进程（线程）数限制，开启隔离环境时必须大于0")
+	flag.IntVar(&define.JudgeCaseParallel, "case-parallel", define.JudgeCaseParallel, "每个提交同时运行的测试用例数，为0时使用CPU核数")
+	flag.StringVar(&define.StorageType, "storage", define.StorageType, "测试数据的存储方式：local、s3")
+	flag.StringVar(&define.StorageDir, "storage-dir", define.StorageDir, "本地存储测试数据的目录")
+	flag.StringVar(&define.S3Endpoint, "s3-endpoint", define.S3Endpoint, "S3兼容的对象存储的地址")
+	flag.StringVar(&define.S3Region, "s3-region", define.S3Region, "S3区域")
+	flag.StringVar(&define.S3Bucket, "s3-bucket", define.S3Bucket, "S3桶")
+	flag.StringVar(&define.S3AccessKey, "s3-access-key", define.S3AccessKey, "S3 access key")
+	flag.StringVar(&define.S3SecretKey, "s3-secret-key", define.S3SecretKey, "S3 secret key")
+	flag.StringVar(&define.StorageCacheDir, "cache-dir", define.StorageCacheDir, "测试数据的缓存目录")
+	flag.StringVar(&define.JudgeProgramCacheDir, "program-cache-dir", define.JudgeProgramCacheDir, "评测程序和交互程序的缓存目录")
+	flag.Parse()
+
+	// 使用命令行参数重新创建存储和判题引擎
+	storage.Default = storage.New()
+	storage.DefaultCache = &storage.Cache{Storage: storage.Default, Dir: define.StorageCacheDir}
+	j := &judge.LocalJudge{
+		InitPath:        define.JudgeInitPath,
+		CgroupRoot:      define.JudgeCgroupRoot,
+		MaxProcs:        define.JudgeMaxProcs,
+		Sandbox:         define.JudgeSandbox,
+		CompileCacheDir: define.JudgeCompileCacheDir,
+	}
+	if err := j.Validate(); err != nil {
+		log.Fatalln(err)
+	}
+
+	langs := worker.AvailableLanguages()
+	if *languages != "" {
+		langs = strings.Split(*languages, ",")
+	}
+	for _, l := range langs {
+		if _, ok := judge.GetLanguage(l); !ok || l == "" {
+			log.Fatalln("unknown language:", l)
+		}
+	}
+	if len(langs) == 0 || *n <= 0 {
+		log.Fatalln("no language or capacity to judge")
+	}
+
+	q := &queue.Queue{RDB: redis.NewClient(&redis.Options{Addr: *redisAddr, Password: *redisPassword})}
+	w := worker.New(q, j, *n, langs)
+	if *id != "" {
+		w.SetID(*id)
+	}
+	// 收到退出信号后不再取出任务，等待正在处理的任务完成
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	w.Run(ctx)
+}
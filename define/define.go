@@ -4,3 +4,81 @@ var (
 	DefaultPage = "1"
 	DefaultSize = "20"
 )
+
+// 比赛开始、结束时间的格式，按照服务器的时区解析
+var TimeLayout = "2006-01-02 15:04:05"
+
+// 比赛中问题的最大数量，按照顺序编号为A、B、C...
+var ContestMaxProblems = 26
+
+// 判题服务中运行的判题节点同时处理的任务数，默认为0，判题服务不执行用户代码，由独立部署的判题节点(cmd/judge-worker)判断
+// 单机部署时可以设置为大于0（如CPU核数），在判题服务中运行判题节点，需要先编译judge-init
+var JudgeWorkerNum = 0
+
+// 判题服务中保存判断结果的协程数
+var JudgeResultWorkerNum = 2
+
+// redis地址，判题服务和判题节点通过redis传递任务和结果
+var RedisAddr = "127.0.0.1:6379"
+
+// 判题节点缓存编译后的评测程序和交互程序的目录
+var JudgeProgramCacheDir = "./cache/programs"
+
+// 编译器的缓存目录（如go的构建缓存），隔离环境中挂载到/cache
+var JudgeCompileCacheDir = "./cache/compile"
+
+// 用户程序的启动进程，负责设置资源限制，通过 go build -o judge-init ./cmd/judge-init 生成
+var JudgeInitPath = "./judge-init"
+
+// 判题使用的cgroup v2目录，为空时不使用cgroup限制用户程序，如"/sys/fs/cgroup/gin_gorm_oj"
+var JudgeCgroupRoot = ""
+
+// 用户程序的进程（线程）数限制，同时设置RLIMIT_NPROC和cgroup的pids.max，开启隔离环境时必须大于0
+// RLIMIT_NPROC按照用户统计，同时运行的用户程序（以及以普通用户运行时判题服务自身的线程）共用该限制
+var JudgeMaxProcs = 256
+
+// 是否在隔离环境（namespace + seccomp）中运行用户程序，仅linux下有效
+var JudgeSandbox = true
+
+// 有测试用例失败时是否结束其余测试用例，结束的测试用例状态为未运行
+// 为false时运行所有测试用例，判断结果与测试用例的运行顺序无关
+var JudgeStopOnFailure = false
+
+// 每个提交同时运行的测试用例数，默认为1依次运行，为0时使用CPU核数
+// 同时运行时用户程序之间会争抢CPU和内存带宽，运行时间可能不稳定
+var JudgeCaseParallel = 1
+
+// 测试数据的存储方式，"local"保存在本地目录，"s3"保存在S3兼容的对象存储（如MinIO）
+var StorageType = "local"
+
+// 本地存储测试数据的目录
+var StorageDir = "./testdata"
+
+// S3兼容的对象存储的地址、区域、桶和密钥，如本地的MinIO为"http://127.0.0.1:9000"
+var (
+	S3Endpoint  = "http://127.0.0.1:9000"
+	S3Region    = "us-east-1"
+	S3Bucket    = "gin-gorm-oj"
+	S3AccessKey = "minioadmin"
+	S3SecretKey = "minioadmin"
+)
+
+// 判题节点缓存测试数据的目录，按照校验和保存，测试数据不变时不会重复下载
+var StorageCacheDir = "./cache/testdata"
+
+// 自定义输入运行(/user/run)的限制：时间(ms)、内存(KB)、输出(KB)和输入(字节)，指定问题时使用问题的时间和内存限制
+var (
+	RunMaxRuntime = 2000
+	RunMaxMem     = 256 * 1024
+	RunMaxOutput  = 64
+	RunMaxInput   = 64 * 1024
+)
+
+// 自定义输入运行等待判题节点返回结果的最长时间(s)
+var RunTimeout = 60
+
+// /submit-status推送判断进度的最长时间(s)
+var SubmitStatusTimeout = 300
+
+// 待判断超过该时间(s)的提交视为判题节点已经丢失，可以强制重新判断
+var RejudgePendingTimeout = 600
@@ -0,0 +1,36 @@
+package dispatch
+
+import (
+	"context"
+	"errors"
+	"gin_gorm_oj/define"
+	"gin_gorm_oj/helper"
+	"gin_gorm_oj/judge"
+	"gin_gorm_oj/queue"
+	"time"
+)
+
+// Compile 由判题节点检查评测程序或交互程序能否编译，判题服务不在本机编译代码。
+// 编译结果写入判题节点的缓存，判题时不需要再次编译
+func Compile(ctx context.Context, language, code string) error {
+	job := &queue.Job{
+		Kind:     queue.KindCompile,
+		Identity: helper.GetUUID(),
+		Language: language,
+		Code:     code,
+	}
+	if err := Queue.Push(ctx, job); err != nil {
+		return errors.New("push compile err:" + err.Error())
+	}
+	res, err := Queue.WaitRunResult(ctx, job.Identity, time.Second*time.Duration(define.RunTimeout))
+	if err != nil {
+		return errors.New("get compile result err:" + err.Error())
+	}
+	if res == nil {
+		return errors.New("等待编译结果超时，请稍后重试")
+	}
+	if res.Status != judge.StatusAccepted {
+		return errors.New(res.Msg)
+	}
+	return nil
+}
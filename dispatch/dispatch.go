@@ -0,0 +1,104 @@
+package dispatch
+
+import (
+	"context"
+	"errors"
+	"gin_gorm_oj/define"
+	"gin_gorm_oj/judge"
+	"gin_gorm_oj/models"
+	"gin_gorm_oj/queue"
+	"log"
+	"os"
+)
+
+// Queue 判题服务使用的任务队列
+var Queue = &queue.Queue{RDB: models.RDB}
+
+// Submit 将待判断的提交连同代码、限制和测试数据放入对应编程语言的队列，由判题节点完成判断
+// 失败时提交判为系统错误，避免一直处于待判断状态，可以通过重新判断恢复
+func Submit(ctx context.Context, identity string) error {
+	job, err := submitJob(identity)
+	if err == nil && job != nil {
+		err = Queue.Push(ctx, job)
+	}
+	if err != nil {
+		e := models.DB.Model(new(models.SubmitBasic)).Where("identity = ? AND status = ?", identity, judge.StatusPending).Updates(map[string]interface{}{
+			"status": judge.StatusSystemError,
+			"msg":    "系统错误:放入判题队列失败",
+		}).Error
+		if e != nil {
+			log.Println("mark submit failed err:", identity, e)
+		}
+	}
+	return err
+}
+
+// submitJob 生成提交的判题任务，提交不是待判断状态时返回nil
+func submitJob(identity string) (*queue.Job, error) {
+	sb := new(models.SubmitBasic)
+	err := models.DB.Where("identity = ?", identity).First(sb).Error
+	if err != nil {
+		return nil, errors.New("get submit err:" + err.Error())
+	}
+	if sb.Status != judge.StatusPending {
+		return nil, nil
+	}
+	pb := new(models.ProblemBasic)
+	err = models.DB.Where("identity = ?", sb.ProblemIdentity).Preload("TestCase").First(pb).Error
+	if err != nil {
+		return nil, errors.New("get problem err:" + err.Error())
+	}
+	lang, ok := judge.GetLanguage(sb.Language)
+	if !ok {
+		lang, _ = judge.GetLanguage(judge.DefaultLanguage)
+	}
+	rules, err := models.GetLanguageLimits(pb.Identity)
+	if err != nil {
+		return nil, errors.New("get language limit err:" + err.Error())
+	}
+	code, err := os.ReadFile(sb.Path)
+	if err != nil {
+		return nil, errors.New("read code err:" + err.Error())
+	}
+
+	job := &queue.Job{
+		Kind:          queue.KindSubmit,
+		Identity:      sb.Identity,
+		Language:      lang.Name,
+		Code:          string(code),
+		Limit:         models.EffectiveLimit(pb, lang, rules),
+		Compare:       pb.Compare(),
+		StopOnFailure: define.JudgeStopOnFailure,
+	}
+	// 评测程序和交互程序由判题节点编译
+	if job.Checker, err = program(pb.CheckerPath, pb.CheckerLanguage); err != nil {
+		return nil, errors.New("read checker err:" + err.Error())
+	}
+	if job.Interactor, err = program(pb.InteractorPath, pb.InteractorLanguage); err != nil {
+		return nil, errors.New("read interactor err:" + err.Error())
+	}
+	for _, tc := range pb.TestCase {
+		job.TestCases = append(job.TestCases, &queue.TestCase{
+			Identity:     tc.Identity,
+			InputKey:     tc.InputKey,
+			InputSha256:  tc.InputSha256,
+			OutputKey:    tc.OutputKey,
+			OutputSha256: tc.OutputSha256,
+			Input:        tc.Input,
+			Output:       tc.Output,
+		})
+	}
+	return job, nil
+}
+
+// program 读取评测程序或交互程序的代码，路径为空时返回nil
+func program(path, language string) (*queue.Program, error) {
+	if path == "" {
+		return nil, nil
+	}
+	code, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return &queue.Program{Language: language, Code: string(code)}, nil
+}
@@ -0,0 +1,86 @@
+package dispatch
+
+import (
+	"context"
+	"errors"
+	"gin_gorm_oj/define"
+	"gin_gorm_oj/judge"
+	"gin_gorm_oj/models"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Rejudge 将提交重置为待判断状态，删除原有的测试用例结果，重新统计相关用户和问题的通过个数后按照保存的代码重新判断
+// 待判断的提交正在判断中，不会重新判断；force为true时待判断超过define.RejudgePendingTimeout的提交同样重新判断
+// 返回放入判题队列的提交数
+func Rejudge(ctx context.Context, sbs []*models.SubmitBasic, force bool) (int, error) {
+	stale := time.Now().Add(-time.Duration(define.RejudgePendingTimeout) * time.Second)
+	identities := make([]string, 0, len(sbs))
+	users := make(map[string]struct{})
+	problems := make(map[string]struct{})
+	for _, sb := range sbs {
+		if sb.Status == judge.StatusPending && !(force && sb.UpdatedAt.Before(stale)) {
+			continue
+		}
+		identities = append(identities, sb.Identity)
+		users[sb.UserIdentity] = struct{}{}
+		problems[sb.ProblemIdentity] = struct{}{}
+	}
+	if len(identities) == 0 {
+		return 0, nil
+	}
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		q := tx.Model(new(models.SubmitBasic))
+		if force {
+			q = q.Where("identity IN ? AND (status <> ? OR updated_at < ?)", identities, judge.StatusPending, stale)
+		} else {
+			q = q.Where("identity IN ? AND status <> ?", identities, judge.StatusPending)
+		}
+		err := q.Updates(map[string]interface{}{
+			"status":   judge.StatusPending,
+			"msg":      "",
+			"run_time": 0,
+			"run_mem":  0,
+		}).Error
+		if err != nil {
+			return errors.New("submitbasic modify err:" + err.Error())
+		}
+		err = tx.Where("submit_identity IN ?", identities).Delete(new(models.SubmitCaseResult)).Error
+		if err != nil {
+			return errors.New("submitcaseresult delete err:" + err.Error())
+		}
+		err = models.UpdatePassNum(tx, keys(users), keys(problems))
+		if err != nil {
+			return errors.New("pass num update err:" + err.Error())
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	// 放入判题队列失败的提交由Submit判为系统错误，可以再次重新判断
+	cnt := 0
+	var pushErr error
+	for _, identity := range identities {
+		if err := Submit(ctx, identity); err != nil {
+			log.Println("rejudge submit err:", identity, err)
+			pushErr = err
+			continue
+		}
+		cnt++
+	}
+	if pushErr != nil {
+		return cnt, errors.New("push submit err:" + pushErr.Error())
+	}
+	return cnt, nil
+}
+
+func keys(m map[string]struct{}) []string {
+	res := make([]string, 0, len(m))
+	for k := range m {
+		res = append(res, k)
+	}
+	return res
+}
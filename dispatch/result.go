@@ -0,0 +1,127 @@
+package dispatch
+
+import (
+	"context"
+	"errors"
+	"gin_gorm_oj/judge"
+	"gin_gorm_oj/models"
+	"gin_gorm_oj/queue"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Start 启动n个协程，保存判题节点返回的判断结果，并定期将下线的判题节点没有完成的任务放回队列
+func Start(n int) {
+	for i := 0; i < n; i++ {
+		go loop()
+	}
+	go requeue()
+}
+
+func requeue() {
+	ticker := time.NewTicker(queue.HeartbeatInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		n, err := Queue.Requeue(context.Background())
+		if err != nil {
+			log.Println("requeue jobs err:", err)
+		}
+		if n > 0 {
+			log.Println("requeued jobs of offline workers:", n)
+		}
+	}
+}
+
+func loop() {
+	ctx := context.Background()
+	for {
+		res, err := Queue.PopResult(ctx, time.Second*5)
+		if err != nil {
+			log.Println("pop result err:", err)
+			time.Sleep(time.Second)
+			continue
+		}
+		if res == nil {
+			continue
+		}
+		saved, err := save(res.Identity, res.Result)
+		if err != nil {
+			log.Println("save result err:", res.Identity, res.Worker, err)
+			continue
+		}
+		if !saved {
+			continue
+		}
+		// 保存后通知等待判断进度的连接，测试用例的结果已经通过判断进度发布
+		err = Queue.Publish(ctx, res.Identity, &queue.Event{
+			Type:   queue.EventResult,
+			Total:  len(res.Result.Cases),
+			Done:   len(res.Result.Cases),
+			Result: &judge.Result{Status: res.Result.Status, Msg: res.Result.Msg, Time: res.Result.Time, Mem: res.Result.Mem},
+		})
+		if err != nil {
+			log.Println("publish result err:", res.Identity, err)
+		}
+	}
+}
+
+// save 更新提交的判断结果，通过时重新统计用户和问题的通过个数，提交已经不是待判断状态时不更新并返回false
+func save(identity string, res *judge.Result) (bool, error) {
+	sb := new(models.SubmitBasic)
+	err := models.DB.Where("identity = ?", identity).First(sb).Error
+	if err != nil {
+		return false, errors.New("get submit err:" + err.Error())
+	}
+	saved := false
+	err = models.DB.Transaction(func(tx *gorm.DB) error {
+		// 只更新仍处于待判断状态的提交，避免重复计数
+		result := tx.Model(new(models.SubmitBasic)).Where("identity = ? AND status = ?", identity, judge.StatusPending).Updates(map[string]interface{}{
+			"status":   res.Status,
+			"msg":      res.Msg,
+			"run_time": res.Time,
+			"run_mem":  res.Mem,
+		})
+		if result.Error != nil {
+			return errors.New("submitbasic modify err:" + result.Error.Error())
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		saved = true
+		// 保存每个测试用例的判断结果
+		err := tx.Where("submit_identity = ?", identity).Delete(new(models.SubmitCaseResult)).Error
+		if err != nil {
+			return errors.New("submitcaseresult delete err:" + err.Error())
+		}
+		if len(res.Cases) > 0 {
+			crs := make([]*models.SubmitCaseResult, 0, len(res.Cases))
+			for _, c := range res.Cases {
+				crs = append(crs, &models.SubmitCaseResult{
+					SubmitIdentity:   identity,
+					TestCaseIdentity: c.Identity,
+					Status:           c.Status,
+					Msg:              c.Msg,
+					RunTime:          c.Time,
+					RunMem:           c.Mem,
+					Stderr:           c.Stderr,
+				})
+			}
+			err = tx.Create(&crs).Error
+			if err != nil {
+				return errors.New("submitcaseresult create err:" + err.Error())
+			}
+		}
+		if res.Status != judge.StatusAccepted {
+			return nil
+		}
+		// 重新统计用户和问题的通过个数
+		err = models.UpdatePassNum(tx, []string{sb.UserIdentity}, []string{sb.ProblemIdentity})
+		if err != nil {
+			return errors.New("pass num update err:" + err.Error())
+		}
+		return nil
+	})
+	return saved, err
+}
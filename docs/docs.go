@@ -176,12 +176,756 @@ const docTemplate = `{
                 }
             }
         },
+        "/admin/contest-create": {
+            "post": {
+                "tags": [
+                    "管理员私有方法"
+                ],
+                "summary": "比赛创建",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "authorization",
+                        "name": "authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "title",
+                        "name": "title",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "比赛说明",
+                        "name": "content",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "开始时间，如2006-01-02 15:04:05",
+                        "name": "start_at",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "结束时间，如2006-01-02 17:04:05",
+                        "name": "end_at",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "比赛中的问题，按照顺序编号为A、B、C...",
+                        "name": "problem_identities",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "{\"code\":\"200\",\"msg\":\"\",\"data\":\"\"}",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/contest-modify": {
+            "put": {
+                "description": "比赛中的问题替换为problem_identities，已经计入比赛的提交不变",
+                "tags": [
+                    "管理员私有方法"
+                ],
+                "summary": "比赛修改",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "authorization",
+                        "name": "authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "identity",
+                        "name": "identity",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "title",
+                        "name": "title",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "比赛说明",
+                        "name": "content",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "开始时间，如2006-01-02 15:04:05",
+                        "name": "start_at",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "结束时间，如2006-01-02 17:04:05",
+                        "name": "end_at",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "比赛中的问题，按照顺序编号为A、B、C...",
+                        "name": "problem_identities",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "{\"code\":\"200\",\"msg\":\"\",\"data\":\"\"}",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/language-limit-delete": {
+            "delete": {
+                "tags": [
+                    "管理员私有方法"
+                ],
+                "summary": "编程语言限制规则删除",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "authorization",
+                        "name": "authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "problem_identity",
+                        "name": "problem_identity",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "language",
+                        "name": "language",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "{\"code\":\"200\",\"msg\":\"\",\"data\":\"\"}",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/language-limit-list": {
+            "get": {
+                "tags": [
+                    "管理员私有方法"
+                ],
+                "summary": "编程语言限制规则列表",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "authorization",
+                        "name": "authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "问题唯一标识，为空时获取全局规则",
+                        "name": "problem_identity",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "{\"code\":\"200\",\"data\":\"\"}",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/language-limit-modify": {
+            "put": {
+                "description": "不存在时创建；max_runtime、max_mem不为0时覆盖倍数；problem_identity为空时修改全局规则",
+                "tags": [
+                    "管理员私有方法"
+                ],
+                "summary": "编程语言限制规则修改",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "authorization",
+                        "name": "authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "problem_identity",
+                        "name": "problem_identity",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "language",
+                        "name": "language",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "number",
+                        "description": "time_factor",
+                        "name": "time_factor",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "number",
+                        "description": "mem_factor",
+                        "name": "mem_factor",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "max_runtime",
+                        "name": "max_runtime",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "max_mem",
+                        "name": "max_mem",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "{\"code\":\"200\",\"msg\":\"\",\"data\":\"\"}",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/problem-checker": {
+            "post": {
+                "description": "评测程序的参数依次为输入文件、标准输出文件和用户输出文件，退出码0为正确，1、2为错误，输出作为判断信息",
+                "tags": [
+                    "管理员私有方法"
+                ],
+                "summary": "上传评测程序",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "authorization",
+                        "name": "authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "问题唯一标识",
+                        "name": "identity",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "评测程序的编程语言，默认go",
+                        "name": "language",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "file",
+                        "description": "评测程序代码",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "{\"code\":\"200\",\"msg\":\"\",\"data\":\"\"}",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/problem-checker-delete": {
+            "delete": {
+                "tags": [
+                    "管理员私有方法"
+                ],
+                "summary": "删除评测程序，恢复按照比较方式判断",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "authorization",
+                        "name": "authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "问题唯一标识",
+                        "name": "identity",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "{\"code\":\"200\",\"msg\":\"\",\"data\":\"\"}",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
         "/admin/problem-create": {
             "post": {
                 "tags": [
                     "管理员私有方法"
                 ],
-                "summary": "问题创建",
+                "summary": "问题创建",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "authorization",
+                        "name": "authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "title",
+                        "name": "title",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "content",
+                        "name": "content",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "max_mem",
+                        "name": "max_mem",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "max_runtime",
+                        "name": "max_runtime",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "输出限制(KB)，为0时使用默认的输出限制",
+                        "name": "max_output",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "category_ids",
+                        "name": "category_ids",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "测试用例，如{\\",
+                        "name": "test_cases",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "输出比较方式：exact、trailing、token、nocase、float，默认exact",
+                        "name": "compare_mode",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "number",
+                        "description": "浮点数比较的绝对误差",
+                        "name": "abs_epsilon",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "number",
+                        "description": "浮点数比较的相对误差",
+                        "name": "rel_epsilon",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "{\"code\":\"200\",\"msg\":\"\",\"data\":\"\"}",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/problem-export": {
+            "get": {
+                "description": "题目包为zip压缩包，包含problem.yaml（标题、限制、比较方式、分类等）、statement.md（题面）、tests目录下的测试用例以及评测程序、交互程序的代码",
+                "tags": [
+                    "管理员私有方法"
+                ],
+                "summary": "导出题目包",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "authorization",
+                        "name": "authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "问题唯一标识",
+                        "name": "identity",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "{\"code\":\"200\",\"msg\":\"\",\"data\":\"\"}",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/problem-import": {
+            "post": {
+                "description": "每个文件为一个题目包，支持/admin/problem-export导出的problem.yaml格式和Polygon的完整题目包(problem.xml)，Polygon的标签导入为分类\n逐个导入，单个题目包导入失败不影响其他题目包，data中依次给出每个题目包的导入结果",
+                "tags": [
+                    "管理员私有方法"
+                ],
+                "summary": "导入题目包",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "authorization",
+                        "name": "authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "题目包zip压缩包，可以上传多个",
+                        "name": "files",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "{\"code\":\"200\",\"msg\":\"\",\"data\":\"\"}",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/problem-interactor": {
+            "post": {
+                "description": "交互程序的参数依次为输入文件和标准输出文件，标准输入输出与用户程序相连，退出码0为正确，1、2为错误，标准错误作为判断信息",
+                "tags": [
+                    "管理员私有方法"
+                ],
+                "summary": "上传交互程序，问题变为交互题",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "authorization",
+                        "name": "authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "问题唯一标识",
+                        "name": "identity",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "交互程序的编程语言，默认go",
+                        "name": "language",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "file",
+                        "description": "交互程序代码",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "{\"code\":\"200\",\"msg\":\"\",\"data\":\"\"}",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/problem-interactor-delete": {
+            "delete": {
+                "tags": [
+                    "管理员私有方法"
+                ],
+                "summary": "删除交互程序，问题恢复为普通题",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "authorization",
+                        "name": "authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "问题唯一标识",
+                        "name": "identity",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "{\"code\":\"200\",\"msg\":\"\",\"data\":\"\"}",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/problem-modify": {
+            "put": {
+                "tags": [
+                    "管理员私有方法"
+                ],
+                "summary": "问题修改",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "authorization",
+                        "name": "authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "identity",
+                        "name": "identity",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "title",
+                        "name": "title",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "content",
+                        "name": "content",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "max_mem",
+                        "name": "max_mem",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "max_runtime",
+                        "name": "max_runtime",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "输出限制(KB)，为0时使用默认的输出限制，不传时不修改",
+                        "name": "max_output",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "category_ids",
+                        "name": "category_ids",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "测试用例，如{\\",
+                        "name": "test_cases",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "输出比较方式：exact、trailing、token、nocase、float，不传时不修改",
+                        "name": "compare_mode",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "number",
+                        "description": "浮点数比较的绝对误差，不传时不修改",
+                        "name": "abs_epsilon",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "number",
+                        "description": "浮点数比较的相对误差，不传时不修改",
+                        "name": "rel_epsilon",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "{\"code\":\"200\",\"msg\":\"\",\"data\":\"\"}",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/problem-test-case-upload": {
+            "post": {
+                "description": "压缩包中的测试用例为成对的输入和标准输出文件，如1.in和1.out（或1.ans），按照文件名排序",
+                "tags": [
+                    "管理员私有方法"
+                ],
+                "summary": "上传测试用例压缩包",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "authorization",
+                        "name": "authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "问题唯一标识",
+                        "name": "identity",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "replace替换原有的测试用例（默认），append追加到原有的测试用例之后",
+                        "name": "mode",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "作为样例的测试用例的文件名（不含扩展名），逗号分隔，如1,2",
+                        "name": "samples",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "file",
+                        "description": "测试用例zip压缩包",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "{\"code\":\"200\",\"msg\":\"\",\"data\":\"\"}",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/rejudge": {
+            "post": {
+                "description": "修改测试用例后按照保存的代码重新判断提交，并重新统计相关用户和问题的通过个数\n指定submit_identity时只重新判断该提交，否则按照问题、用户、状态和编程语言筛选，至少指定一个条件；待判断的提交不会重新判断\nforce为true时待判断超过RejudgePendingTimeout的提交视为判题节点已经丢失，同样重新判断",
+                "tags": [
+                    "管理员私有方法"
+                ],
+                "summary": "重新判断提交",
                 "parameters": [
                     {
                         "type": "string",
@@ -192,52 +936,39 @@ const docTemplate = `{
                     },
                     {
                         "type": "string",
-                        "description": "title",
-                        "name": "title",
-                        "in": "formData",
-                        "required": true
+                        "description": "提交唯一标识",
+                        "name": "submit_identity",
+                        "in": "formData"
                     },
                     {
                         "type": "string",
-                        "description": "content",
-                        "name": "content",
-                        "in": "formData",
-                        "required": true
+                        "description": "问题唯一标识",
+                        "name": "problem_identity",
+                        "in": "formData"
                     },
                     {
-                        "type": "integer",
-                        "description": "max_mem",
-                        "name": "max_mem",
-                        "in": "formData",
-                        "required": true
+                        "type": "string",
+                        "description": "用户唯一标识",
+                        "name": "user_identity",
+                        "in": "formData"
                     },
                     {
                         "type": "integer",
-                        "description": "max_runtime",
-                        "name": "max_runtime",
-                        "in": "formData",
-                        "required": true
+                        "description": "判断状态",
+                        "name": "status",
+                        "in": "formData"
                     },
                     {
-                        "type": "array",
-                        "items": {
-                            "type": "string"
-                        },
-                        "collectionFormat": "multi",
-                        "description": "category_ids",
-                        "name": "category_ids",
+                        "type": "string",
+                        "description": "编程语言",
+                        "name": "language",
                         "in": "formData"
                     },
                     {
-                        "type": "array",
-                        "items": {
-                            "type": "string"
-                        },
-                        "collectionFormat": "multi",
-                        "description": "test_cases",
-                        "name": "test_cases",
-                        "in": "formData",
-                        "required": true
+                        "type": "boolean",
+                        "description": "是否强制重新判断长时间待判断的提交",
+                        "name": "force",
+                        "in": "formData"
                     }
                 ],
                 "responses": {
@@ -250,12 +981,13 @@ const docTemplate = `{
                 }
             }
         },
-        "/admin/problem-modify": {
-            "put": {
+        "/admin/worker-list": {
+            "get": {
+                "description": "返回在线的判题节点（最近15秒内有心跳）和各编程语言等待处理的任务数",
                 "tags": [
                     "管理员私有方法"
                 ],
-                "summary": "问题修改",
+                "summary": "判题节点列表",
                 "parameters": [
                     {
                         "type": "string",
@@ -263,67 +995,73 @@ const docTemplate = `{
                         "name": "authorization",
                         "in": "header",
                         "required": true
-                    },
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "{\"code\":\"200\",\"data\":\"\"}",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/contest-detail": {
+            "get": {
+                "description": "比赛开始后才返回比赛中的问题",
+                "tags": [
+                    "公共方法"
+                ],
+                "summary": "比赛详情",
+                "parameters": [
                     {
                         "type": "string",
-                        "description": "identity",
+                        "description": "contest identity",
                         "name": "identity",
-                        "in": "formData",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "title",
-                        "name": "title",
-                        "in": "formData",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "content",
-                        "name": "content",
-                        "in": "formData",
+                        "in": "query",
                         "required": true
-                    },
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "{\"code\":\"200\",\"data\":\"\"}",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/contest-list": {
+            "get": {
+                "tags": [
+                    "公共方法"
+                ],
+                "summary": "比赛列表",
+                "parameters": [
                     {
                         "type": "integer",
-                        "description": "max_mem",
-                        "name": "max_mem",
-                        "in": "formData",
-                        "required": true
+                        "description": "请输入当前页面，默认第一页",
+                        "name": "page",
+                        "in": "query"
                     },
                     {
                         "type": "integer",
-                        "description": "max_runtime",
-                        "name": "max_runtime",
-                        "in": "formData",
-                        "required": true
-                    },
-                    {
-                        "type": "array",
-                        "items": {
-                            "type": "string"
-                        },
-                        "collectionFormat": "multi",
-                        "description": "category_ids",
-                        "name": "category_ids",
-                        "in": "formData"
+                        "description": "size",
+                        "name": "size",
+                        "in": "query"
                     },
                     {
-                        "type": "array",
-                        "items": {
-                            "type": "string"
-                        },
-                        "collectionFormat": "multi",
-                        "description": "test_cases",
-                        "name": "test_cases",
-                        "in": "formData",
-                        "required": true
+                        "type": "string",
+                        "description": "keyword",
+                        "name": "keyword",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "{\"code\":\"200\",\"msg\":\"\",\"data\":\"\"}",
+                        "description": "{\"code\":\"200\",\"data\":\"\"}",
                         "schema": {
                             "type": "string"
                         }
@@ -533,6 +1271,32 @@ const docTemplate = `{
                 }
             }
         },
+        "/submit-detail": {
+            "get": {
+                "description": "只返回样例的标准错误，其他测试用例的标准错误对所有用户（包括提交者）隐藏",
+                "tags": [
+                    "公共方法"
+                ],
+                "summary": "提交详情，包含每个测试用例的判断结果",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "submit identity",
+                        "name": "identity",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "{\"code\":\"200\",\"data\":\"\"}",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
         "/submit-list": {
             "get": {
                 "tags": [
@@ -575,6 +1339,32 @@ const docTemplate = `{
                 }
             }
         },
+        "/submit-status": {
+            "get": {
+                "description": "以text/event-stream推送判断进度：judging开始判断，case每个测试用例结束（包含total、done和测试用例的结果，不包含标准错误），result判断结果已经保存（之后关闭连接）\n提交已经判断完成时直接推送result，等待超过define.SubmitStatusTimeout时关闭连接，客户端可以重新连接",
+                "tags": [
+                    "公共方法"
+                ],
+                "summary": "提交的判断进度（Server-Sent Events）",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "submit identity",
+                        "name": "identity",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "event: result",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
         "/user-detail": {
             "get": {
                 "tags": [
@@ -599,8 +1389,93 @@ const docTemplate = `{
                 }
             }
         },
+        "/user/contest-register": {
+            "post": {
+                "description": "比赛结束前可以报名，报名后在比赛进行期间对比赛中问题的提交计入比赛",
+                "tags": [
+                    "用户私有方法"
+                ],
+                "summary": "比赛报名",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "authorization",
+                        "name": "authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "contest identity",
+                        "name": "identity",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "{\"code\":\"200\",\"msg\":\"\",\"data\":\"\"}",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/run": {
+            "post": {
+                "description": "编译代码并以input为标准输入运行，返回标准输出、标准错误、运行时间和内存，不创建提交，不影响提交数和排名\n指定问题时使用问题在该编程语言下的时间和内存限制，未开始的比赛中的问题视为不存在",
+                "tags": [
+                    "用户私有方法"
+                ],
+                "summary": "使用自定义输入运行代码",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "authorization",
+                        "name": "authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "problem_identity",
+                        "name": "problem_identity",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "编程语言：go、c、cpp、python、java，默认go",
+                        "name": "language",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "code",
+                        "name": "code",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "标准输入",
+                        "name": "input",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "{\"code\":\"200\",\"msg\":\"\",\"data\":\"\"}",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
         "/user/submit": {
             "post": {
+                "description": "提交后处于待判断状态(-1)，判断完成后通过提交详情查看结果",
                 "tags": [
                     "用户私有方法"
                 ],
@@ -620,6 +1495,12 @@ const docTemplate = `{
                         "in": "query",
                         "required": true
                     },
+                    {
+                        "type": "string",
+                        "description": "编程语言：go、c、cpp、python、java，默认go",
+                        "name": "language",
+                        "in": "query"
+                    },
                     {
                         "description": "code",
                         "name": "code",
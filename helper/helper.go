@@ -84,10 +84,10 @@ func GetRand() string {
 	return s
 }
 
-// 代码保存
-func CodeSave(code []byte) (string, error) {
+// 代码保存，fileName为对应编程语言的代码文件名
+func CodeSave(code []byte, fileName string) (string, error) {
 	dirName := "code/" + GetUUID()
-	path := dirName + "/main.go"
+	path := dirName + "/" + fileName
 	err := os.Mkdir(dirName, 0777)
 	if err != nil {
 		return "", err
@@ -0,0 +1,104 @@
+package judge
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// 用于生成cgroup的名字
+var cgroupSeq int64
+
+// cgroup 一次运行使用的cgroup v2子目录
+type cgroup struct {
+	path string
+}
+
+// newCgroup 在root下创建子cgroup，并设置内存和进程数限制，为0时不限制
+func newCgroup(root string, memory int64, pids int64) (*cgroup, error) {
+	// 开启子cgroup的memory和pids控制器，已经开启时忽略错误
+	os.WriteFile(filepath.Join(root, "cgroup.subtree_control"), []byte("+memory +pids"), 0644)
+	name := fmt.Sprintf("run-%d-%d", os.Getpid(), atomic.AddInt64(&cgroupSeq, 1))
+	cg := &cgroup{path: filepath.Join(root, name)}
+	if err := os.Mkdir(cg.path, 0755); err != nil {
+		return nil, err
+	}
+	files := map[string]string{}
+	if memory > 0 {
+		files["memory.max"] = strconv.FormatInt(memory, 10)
+		files["memory.swap.max"] = "0"
+	}
+	if pids > 0 {
+		files["pids.max"] = strconv.FormatInt(pids, 10)
+	}
+	for name, value := range files {
+		err := os.WriteFile(filepath.Join(cg.path, name), []byte(value), 0644)
+		if err != nil && name != "memory.swap.max" {
+			cg.remove()
+			return nil, err
+		}
+	}
+	return cg, nil
+}
+
+// peak 内存使用峰值(KB)，内核不支持memory.peak时返回0
+func (cg *cgroup) peak() int {
+	data, err := os.ReadFile(filepath.Join(cg.path, "memory.peak"))
+	if err != nil {
+		return 0
+	}
+	n, _ := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	return int(n / 1024)
+}
+
+// cpuTime cgroup中所有进程使用的CPU时间(ms)，读取失败时返回0
+func (cg *cgroup) cpuTime() int {
+	data, err := os.ReadFile(filepath.Join(cg.path, "cpu.stat"))
+	if err != nil {
+		return 0
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "usage_usec" {
+			n, _ := strconv.ParseInt(fields[1], 10, 64)
+			return int(n / 1000)
+		}
+	}
+	return 0
+}
+
+// oomKilled 是否有进程因为超过memory.max被杀死
+func (cg *cgroup) oomKilled() bool {
+	data, err := os.ReadFile(filepath.Join(cg.path, "memory.events"))
+	if err != nil {
+		return false
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "oom_kill" {
+			return fields[1] != "0"
+		}
+	}
+	return false
+}
+
+// remove 结束cgroup中剩余的进程并删除cgroup
+func (cg *cgroup) remove() {
+	if cg == nil {
+		return
+	}
+	os.WriteFile(filepath.Join(cg.path, "cgroup.kill"), []byte("1"), 0644)
+	// 进程退出后才能删除cgroup
+	for i := 0; i < 10; i++ {
+		if err := os.Remove(cg.path); err == nil || os.IsNotExist(err) {
+			return
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+	log.Println("remove cgroup err:", cg.path)
+}
@@ -0,0 +1,60 @@
+package judge
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// 评测程序运行的最长时间
+	checkerTimeout = time.Second * 10
+	// 评测程序输出的信息最多保留的长度
+	checkerMsgLimit = 1024
+)
+
+// check 调用评测程序判断输出是否正确
+// 评测程序的参数依次为输入文件、标准输出文件和用户输出文件，兼容testlib的退出码：
+// 0-正确，1、2-错误，其他为评测程序自身的错误，标准输出和标准错误作为判断信息
+func (j *LocalJudge) check(ctx context.Context, checker *Program, tc *TestCase, output string) (bool, string, error) {
+	dir, files, err := caseFiles(tc, output)
+	if err != nil {
+		return false, "", err
+	}
+	defer os.RemoveAll(dir)
+
+	ctx, cancel := context.WithTimeout(ctx, checkerTimeout)
+	defer cancel()
+	run := checker.Language.Run
+	cmd := exec.CommandContext(ctx, run[0], append(run[1:len(run):len(run)], files...)...)
+	cmd.Dir = checker.Dir
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	err = cmd.Run()
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return verdict(0, out.String())
+	case errors.As(err, &exitErr) && !errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return verdict(exitErr.ExitCode(), out.String())
+	}
+	return false, out.String(), errors.New("checker failed: " + err.Error())
+}
+
+// verdict 根据评测程序（交互程序）的退出码给出判断结果
+func verdict(code int, msg string) (bool, string, error) {
+	msg = strings.TrimSpace(truncate(msg, checkerMsgLimit))
+	switch code {
+	case 0:
+		return true, msg, nil
+	case 1, 2:
+		return false, msg, nil
+	}
+	return false, msg, errors.New("checker failed: exit code " + strconv.Itoa(code) + " " + msg)
+}
@@ -0,0 +1,229 @@
+package judge
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// 输出比较方式
+const (
+	CompareExact    = "exact"    // 逐字节比较
+	CompareTrailing = "trailing" // 忽略行尾空白和末尾空行
+	CompareToken    = "token"    // 按空白分隔后逐个比较
+	CompareNoCase   = "nocase"   // 按空白分隔后忽略大小写比较
+	CompareFloat    = "float"    // 按空白分隔，数字在误差范围内视为相等
+)
+
+// compareNone 不比较输出，保存程序的输出，只用于自定义输入运行
+const compareNone = "none"
+
+// 浮点数比较时未设置误差使用的绝对误差
+const defaultEpsilon = 1e-6
+
+// CompareModes 支持的输出比较方式
+var CompareModes = map[string]bool{
+	CompareExact:    true,
+	CompareTrailing: true,
+	CompareToken:    true,
+	CompareNoCase:   true,
+	CompareFloat:    true,
+}
+
+// Compare 输出比较方式，Mode为空时逐字节比较
+// AbsEpsilon、RelEpsilon只在浮点数比较时使用，满足其一即视为相等
+// Checker不为nil时由评测程序判断，忽略Mode
+// Interactor不为nil时为交互题，由交互程序判断
+type Compare struct {
+	Mode       string   `json:"compare_mode"`
+	AbsEpsilon float64  `json:"abs_epsilon"`
+	RelEpsilon float64  `json:"rel_epsilon"`
+	Checker    *Program `json:"-"`
+	Interactor *Program `json:"-"`
+}
+
+// Equal 判断程序输出与标准输出是否一致
+func (c Compare) Equal(expected, actual string) bool {
+	sc := c.stream(strings.NewReader(expected))
+	sc.Write([]byte(actual))
+	return sc.equal()
+}
+
+// streamCompare 在程序输出的同时按照比较方式与标准输出比较，出现不一致时可以提前结束程序
+// 标准输出也是边比较边读取，不需要全部读入内存
+type streamCompare struct {
+	cmp      Compare
+	expected *bufio.Reader
+	cur      []byte // 还没有结束的行或记号
+	failed   bool
+}
+
+// stream 创建与expected比较的streamCompare
+func (c Compare) stream(expected io.Reader) *streamCompare {
+	return &streamCompare{cmp: c, expected: bufio.NewReader(expected)}
+}
+
+func (sc *streamCompare) Write(p []byte) (int, error) {
+	if sc.failed {
+		return len(p), nil
+	}
+	switch sc.cmp.Mode {
+	case CompareTrailing:
+		for _, b := range p {
+			if b == '\n' {
+				sc.line()
+			} else {
+				sc.cur = append(sc.cur, b)
+			}
+		}
+	case CompareToken, CompareNoCase, CompareFloat:
+		for _, b := range p {
+			if isSpace(rune(b)) {
+				sc.token()
+			} else {
+				sc.cur = append(sc.cur, b)
+			}
+		}
+	default:
+		for q := p; len(q) > 0; {
+			n := len(q)
+			if n > sc.expected.Size() {
+				n = sc.expected.Size()
+			}
+			// 标准输出结束时Peek返回的数据少于n
+			e, _ := sc.expected.Peek(n)
+			if len(e) == 0 || !bytes.Equal(e, q[:len(e)]) {
+				sc.failed = true
+				break
+			}
+			sc.expected.Discard(len(e))
+			q = q[len(e):]
+		}
+	}
+	return len(p), nil
+}
+
+// nextLine 读取标准输出的下一行并去掉行尾空白，标准输出结束时返回false
+func (sc *streamCompare) nextLine() (string, bool) {
+	l, err := sc.expected.ReadString('\n')
+	if l == "" && err != nil {
+		return "", false
+	}
+	return strings.TrimRight(l, " \t\r\n"), true
+}
+
+// line 比较一行，标准输出之后只能有空行
+func (sc *streamCompare) line() {
+	l := strings.TrimRight(string(sc.cur), " \t\r")
+	sc.cur = sc.cur[:0]
+	e, _ := sc.nextLine()
+	if l != e {
+		sc.failed = true
+	}
+}
+
+// nextToken 读取标准输出的下一个记号，标准输出结束时返回false
+func (sc *streamCompare) nextToken() (string, bool) {
+	var t []byte
+	for {
+		b, err := sc.expected.ReadByte()
+		if err != nil {
+			return string(t), len(t) > 0
+		}
+		if !isSpace(rune(b)) {
+			t = append(t, b)
+		} else if len(t) > 0 {
+			return string(t), true
+		}
+	}
+}
+
+// token 比较一个记号
+func (sc *streamCompare) token() {
+	if len(sc.cur) == 0 {
+		return
+	}
+	t := string(sc.cur)
+	sc.cur = sc.cur[:0]
+	e, ok := sc.nextToken()
+	if !ok {
+		sc.failed = true
+		return
+	}
+	switch sc.cmp.Mode {
+	case CompareNoCase:
+		ok = strings.EqualFold(e, t)
+	case CompareFloat:
+		ok = sc.cmp.equalFloat(e, t)
+	default:
+		ok = e == t
+	}
+	if !ok {
+		sc.failed = true
+	}
+}
+
+// equal 程序输出结束后判断是否与标准输出一致
+func (sc *streamCompare) equal() bool {
+	switch sc.cmp.Mode {
+	case CompareTrailing:
+		if len(sc.cur) > 0 {
+			sc.line()
+		}
+		// 标准输出剩余的只能是空行
+		for !sc.failed {
+			l, ok := sc.nextLine()
+			if !ok {
+				break
+			}
+			sc.failed = l != ""
+		}
+		return !sc.failed
+	case CompareToken, CompareNoCase, CompareFloat:
+		sc.token()
+		if sc.failed {
+			return false
+		}
+		_, more := sc.nextToken()
+		return !more
+	default:
+		if sc.failed {
+			return false
+		}
+		_, err := sc.expected.Peek(1)
+		return err == io.EOF
+	}
+}
+
+// isSpace 分隔记号的空白字符
+func isSpace(r rune) bool {
+	switch r {
+	case ' ', '\t', '\n', '\r', '\v', '\f':
+		return true
+	}
+	return false
+}
+
+// equalFloat 两个记号都是数字时按误差比较，否则逐字节比较
+func (c Compare) equalFloat(e, a string) bool {
+	if e == a {
+		return true
+	}
+	x, err := strconv.ParseFloat(e, 64)
+	if err != nil {
+		return false
+	}
+	y, err := strconv.ParseFloat(a, 64)
+	if err != nil || math.IsNaN(x) || math.IsNaN(y) {
+		return false
+	}
+	abs, rel := c.AbsEpsilon, c.RelEpsilon
+	if abs == 0 && rel == 0 {
+		abs = defaultEpsilon
+	}
+	diff := math.Abs(x - y)
+	return diff <= abs || diff <= rel*math.Abs(x)
+}
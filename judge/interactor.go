@@ -0,0 +1,119 @@
+package judge
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+)
+
+// 交互程序至少可以使用的内存(KB)
+const interactorMinMem = 256 * 1024
+
+// interactor 与用户程序双向连接的交互程序
+// 交互程序的参数依次为输入文件和标准输出文件，从标准输入读取用户程序的输出，
+// 向标准输出写入用户程序的输入，退出码与评测程序相同，标准错误作为判断信息
+type interactor struct {
+	sb    *sandbox
+	dir   string
+	msg   bytes.Buffer
+	pipes []*os.File // 用户程序一端的管道，用户程序启动后关闭
+}
+
+// interact 启动交互程序，并将用户程序cmd的标准输入输出连接到交互程序
+// 交互程序使用与用户程序相同的时间限制，不在隔离环境中运行
+func (j *LocalJudge) interact(ctx context.Context, prog *Program, tc *TestCase, limit Limit, cmd *exec.Cmd) (*interactor, error) {
+	if limit.MaxMem < interactorMinMem {
+		limit.MaxMem = interactorMinMem
+	}
+	it := new(interactor)
+	dir, files, err := caseFiles(tc)
+	if err != nil {
+		return nil, err
+	}
+	it.dir = dir
+	plain := &LocalJudge{InitPath: j.InitPath, CgroupRoot: j.CgroupRoot}
+	it.sb, err = plain.sandbox(ctx, prog, limit)
+	if err != nil {
+		it.remove()
+		return nil, err
+	}
+	icmd := it.sb.cmd
+	icmd.Args = append(icmd.Args, files...)
+
+	// 交互程序 -> 用户程序
+	userIn, interOut, err := os.Pipe()
+	if err != nil {
+		it.remove()
+		return nil, err
+	}
+	// 用户程序 -> 交互程序
+	interIn, userOut, err := os.Pipe()
+	if err != nil {
+		userIn.Close()
+		interOut.Close()
+		it.remove()
+		return nil, err
+	}
+	icmd.Stdin = interIn
+	icmd.Stdout = interOut
+	icmd.Stderr = &it.msg
+	cmd.Stdin = userIn
+	cmd.Stdout = userOut
+	it.pipes = []*os.File{userIn, userOut}
+	err = it.sb.start()
+	// 交互程序一端的管道只由交互程序持有，这样一方退出后另一方可以读到EOF
+	interIn.Close()
+	interOut.Close()
+	if err != nil {
+		it.remove()
+		return nil, err
+	}
+	return it, nil
+}
+
+// started 用户程序启动后关闭本进程持有的管道
+func (it *interactor) started() {
+	for _, f := range it.pipes {
+		f.Close()
+	}
+	it.pipes = nil
+}
+
+// errInteractorTimeout 用户程序退出后交互程序没有在墙上时间限制内退出
+var errInteractorTimeout = errors.New("interactor timeout")
+
+// wait 等待交互程序退出并给出判断结果，ctx结束时结束交互程序所在的进程组
+func (it *interactor) wait(ctx context.Context) (bool, string, error) {
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			it.sb.kill()
+		case <-done:
+		}
+	}()
+	err := it.sb.wait()
+	close(done)
+	if ctx.Err() != nil {
+		return false, it.msg.String(), errInteractorTimeout
+	}
+	if err != nil {
+		return false, it.msg.String(), errors.New("interactor failed: " + err.Error())
+	}
+	return verdict(it.sb.exitCode(), it.msg.String())
+}
+
+// remove 清理交互程序的运行环境，交互程序没有退出时将其结束
+func (it *interactor) remove() {
+	it.started()
+	if it.sb != nil {
+		if cmd := it.sb.cmd; cmd.Process != nil && cmd.ProcessState == nil {
+			it.sb.kill()
+			cmd.Wait()
+		}
+		it.sb.remove()
+	}
+	os.RemoveAll(it.dir)
+}
@@ -0,0 +1,235 @@
+package judge
+
+import (
+	"context"
+	"gin_gorm_oj/define"
+	"sync"
+	"sync/atomic"
+)
+
+// 判断状态
+// -1-待判断，1-正确，2-错误，3-超时，4-超内存，5-编译错误，6-系统错误，7-运行错误(非法系统调用)，
+// 8-运行错误(非0退出码或被信号终止)，9-输出超限，10-未运行(已有测试用例失败)
+const (
+	StatusPending      = -1
+	StatusAccepted     = 1
+	StatusWrongAnswer  = 2
+	StatusTimeLimit    = 3
+	StatusMemoryLimit  = 4
+	StatusCompileError = 5
+	StatusSystemError  = 6
+	// 使用了seccomp白名单之外的系统调用
+	StatusRestrictedSyscall = 7
+	StatusRuntimeError      = 8
+	StatusOutputLimit       = 9
+	StatusSkipped           = 10
+)
+
+// statusPriority 汇总判断结果时各状态的优先级，多个测试用例失败时取优先级最高的状态，
+// 优先级相同时取靠前的测试用例
+var statusPriority = map[int]int{
+	StatusSkipped:           0,
+	StatusAccepted:          1,
+	StatusWrongAnswer:       2,
+	StatusOutputLimit:       3,
+	StatusTimeLimit:         4,
+	StatusMemoryLimit:       5,
+	StatusRuntimeError:      6,
+	StatusRestrictedSyscall: 7,
+	StatusSystemError:       8,
+}
+
+// TestCase 测试用例
+type TestCase struct {
+	Identity string
+	Input    string
+	Output   string
+	// InputFile、OutputFile不为空时从文件读取输入和标准输出，忽略Input、Output
+	InputFile  string
+	OutputFile string
+}
+
+// Limit 运行限制，MaxRuntime单位为ms，MaxMem、MaxOutput单位为KB，MaxOutput为0时使用默认的输出限制
+type Limit struct {
+	MaxRuntime int `json:"max_runtime"`
+	MaxMem     int `json:"max_mem"`
+	MaxOutput  int `json:"max_output"`
+}
+
+// Submission 待判断的提交
+type Submission struct {
+	Path      string  // 代码路径
+	Language  string  // 编程语言，为空时使用默认语言
+	Limit     Limit   // 该编程语言下的运行限制
+	Compare   Compare // 输出比较方式
+	TestCases []*TestCase
+	// StopOnFailure 有测试用例失败时结束其余测试用例，结束的测试用例状态为未运行
+	StopOnFailure bool
+	// Parallel 同时运行的测试用例数，不大于1时依次运行
+	Parallel int
+	// Progress 每个测试用例结束时调用，i为测试用例的下标，按照结束的顺序调用且不会同时调用
+	Progress func(i int, c *CaseResult)
+}
+
+// Program 编译后可以运行的程序
+type Program struct {
+	Dir      string // 代码和编译产物所在目录
+	Language *Language
+}
+
+// CaseResult 单个测试用例的判断结果，Time为CPU时间(ms)，Mem为内存峰值(KB)
+type CaseResult struct {
+	Identity string `json:"identity"`
+	Status   int    `json:"status"`
+	Msg      string `json:"msg"`
+	Time     int    `json:"time"`
+	Mem      int    `json:"mem"`
+	Stderr   string `json:"stderr"`           // 用户程序的标准错误，只保留开头部分
+	Stdout   string `json:"stdout,omitempty"` // 用户程序的标准输出，只在自定义输入运行时返回
+}
+
+// Result 一次提交的判断结果，Time和Mem取所有测试用例中的最大值
+type Result struct {
+	Status int           `json:"status"`
+	Msg    string        `json:"msg"`
+	Time   int           `json:"time"`
+	Mem    int           `json:"mem"`
+	Cases  []*CaseResult `json:"cases"`
+}
+
+// CompileError 编译错误，Msg为编译器的输出
+type CompileError struct {
+	Msg string
+}
+
+func (e *CompileError) Error() string {
+	return e.Msg
+}
+
+// Judge 判题引擎
+type Judge interface {
+	// Compile 编译代码，编译失败时返回*CompileError
+	Compile(ctx context.Context, lang *Language, path string) (*Program, error)
+	// Run 使用一个测试用例运行程序，按照cmp比较输出并给出判断结果
+	Run(ctx context.Context, prog *Program, tc *TestCase, limit Limit, cmp Compare) *CaseResult
+}
+
+// Default 默认的判题引擎，部署时可以替换为其他实现
+var Default Judge = &LocalJudge{
+	InitPath:        define.JudgeInitPath,
+	CgroupRoot:      define.JudgeCgroupRoot,
+	MaxProcs:        define.JudgeMaxProcs,
+	Sandbox:         define.JudgeSandbox,
+	CompileCacheDir: define.JudgeCompileCacheDir,
+}
+
+// Execute 编译代码并以input为标准输入运行，不与标准输出比较，结果中包含程序的标准输出和标准错误
+// 程序正常退出时状态为正确(1)，输出超过limit.MaxOutput时为输出超限
+func Execute(ctx context.Context, j Judge, path, language, input string, limit Limit) *CaseResult {
+	lang, ok := GetLanguage(language)
+	if !ok {
+		return &CaseResult{Status: StatusCompileError, Msg: "不支持的编程语言:" + language}
+	}
+	prog, err := j.Compile(ctx, lang, path)
+	if err != nil {
+		return &CaseResult{Status: StatusCompileError, Msg: err.Error()}
+	}
+	res := j.Run(ctx, prog, &TestCase{Input: input}, limit, Compare{Mode: compareNone})
+	if res.Status == StatusAccepted {
+		res.Msg = "运行完成"
+	}
+	return res
+}
+
+// Do 使用判题引擎判断一次提交
+func Do(ctx context.Context, j Judge, s *Submission) *Result {
+	lang, ok := GetLanguage(s.Language)
+	if !ok {
+		return &Result{
+			Status: StatusCompileError,
+			Msg:    "不支持的编程语言:" + s.Language,
+		}
+	}
+	prog, err := j.Compile(ctx, lang, s.Path)
+	if err != nil {
+		return &Result{
+			Status: StatusCompileError,
+			Msg:    err.Error(),
+		}
+	}
+
+	// 通过协程执行测试，每个协程只写自己的结果，同时运行的协程数不超过Parallel
+	parallel := s.Parallel
+	if parallel < 1 {
+		parallel = 1
+	}
+	sem := make(chan struct{}, parallel)
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	cases := make([]*CaseResult, len(s.TestCases))
+	var wg sync.WaitGroup
+	var failed int32
+	var progressMu sync.Mutex
+	progress := func(i int) {
+		if s.Progress == nil {
+			return
+		}
+		progressMu.Lock()
+		defer progressMu.Unlock()
+		s.Progress(i, cases[i])
+	}
+	for i, tc := range s.TestCases {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int, tc *TestCase) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			skipped := &CaseResult{Identity: tc.Identity, Status: StatusSkipped, Msg: "未运行"}
+			if runCtx.Err() != nil {
+				cases[i] = skipped
+				progress(i)
+				return
+			}
+			c := j.Run(runCtx, prog, tc, s.Limit, s.Compare)
+			if c.Status != StatusAccepted {
+				// 第一个失败的测试用例保留结果，之后失败的测试用例可能是被结束的
+				if atomic.CompareAndSwapInt32(&failed, 0, 1) {
+					if s.StopOnFailure {
+						cancel()
+					}
+				} else if runCtx.Err() != nil {
+					c = skipped
+				}
+			}
+			cases[i] = c
+			progress(i)
+		}(i, tc)
+	}
+	wg.Wait()
+	if ctx.Err() != nil {
+		return &Result{
+			Status: StatusSystemError,
+			Msg:    "判题被取消:" + ctx.Err().Error(),
+			Cases:  cases,
+		}
+	}
+
+	res := &Result{
+		Status: StatusAccepted,
+		Msg:    "答案正确",
+		Cases:  cases,
+	}
+	for _, c := range cases {
+		if c.Time > res.Time {
+			res.Time = c.Time
+		}
+		if c.Mem > res.Mem {
+			res.Mem = c.Mem
+		}
+		if statusPriority[c.Status] > statusPriority[res.Status] {
+			res.Status = c.Status
+			res.Msg = c.Msg
+		}
+	}
+	return res
+}
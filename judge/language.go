@@ -0,0 +1,102 @@
+package judge
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 默认的编程语言，兼容没有记录语言的提交
+const DefaultLanguage = "go"
+
+// Language 编程语言，编译和运行命令都在代码所在目录中执行
+type Language struct {
+	Name       string   `json:"name"`
+	SourceFile string   `json:"source_file"` // 代码文件名
+	Compile    []string `json:"compile"`     // 编译命令，为空时不需要编译
+	Run        []string `json:"run"`         // 运行命令
+	// AddressSpace 虚拟内存在内存限制之外预留的空间(B)，小于0时不限制虚拟内存
+	AddressSpace int64 `json:"address_space"`
+	// MaxMemArg 运行时限制内存的参数，%d为内存限制(MB)，插入到运行命令的第一个参数之后，如java的-Xmx
+	MaxMemArg string `json:"max_mem_arg"`
+	// OOMExitCode 超过MaxMemArg的限制时程序的退出码，判断为超内存，为0时不使用
+	OOMExitCode int `json:"oom_exit_code"`
+	// Syscalls 运行时额外需要的系统调用
+	Syscalls []uintptr `json:"-"`
+	// TimeFactor、MemFactor 时间和内存限制的默认倍数，可以被全局和问题的规则覆盖
+	TimeFactor float64 `json:"time_factor"`
+	MemFactor  float64 `json:"mem_factor"`
+}
+
+// Languages 支持的编程语言
+var Languages = map[string]*Language{
+	"go": {
+		Name:       "go",
+		SourceFile: "main.go",
+		Compile:    []string{"go", "build", "-o", "main", "main.go"},
+		Run:        []string{"./main"},
+		// go运行时启动时需要保留较大的地址空间
+		AddressSpace: 1 << 30,
+		TimeFactor:   1,
+		MemFactor:    1,
+	},
+	"c": {
+		Name:         "c",
+		SourceFile:   "main.c",
+		Compile:      []string{"gcc", "-O2", "-std=c11", "-o", "main", "main.c", "-lm"},
+		Run:          []string{"./main"},
+		AddressSpace: 16 << 20,
+		TimeFactor:   1,
+		MemFactor:    1,
+	},
+	"cpp": {
+		Name:         "cpp",
+		SourceFile:   "main.cpp",
+		Compile:      []string{"g++", "-O2", "-std=c++17", "-o", "main", "main.cpp"},
+		Run:          []string{"./main"},
+		AddressSpace: 16 << 20,
+		TimeFactor:   1,
+		MemFactor:    1,
+	},
+	"python": {
+		Name:       "python",
+		SourceFile: "main.py",
+		// 只检查语法错误
+		Compile:      []string{"python3", "-m", "py_compile", "main.py"},
+		Run:          []string{"python3", "main.py"},
+		AddressSpace: 64 << 20,
+		TimeFactor:   3,
+		MemFactor:    2,
+	},
+	"java": {
+		Name:       "java",
+		SourceFile: "Main.java",
+		Compile:    []string{"javac", "-encoding", "UTF-8", "Main.java"},
+		Run:        []string{"java", "-XX:+UseSerialGC", "-XX:-UsePerfData", "-XX:+ExitOnOutOfMemoryError", "-Xss64m", "-cp", ".", "Main"},
+		// jvm按照堆大小保留地址空间，不限制虚拟内存，由-Xmx限制堆内存，
+		// 堆内存不足时以退出码3退出，没有配置cgroup时同样有内存限制
+		AddressSpace: -1,
+		MaxMemArg:    "-Xmx%dm",
+		OOMExitCode:  3,
+		Syscalls:     javaSyscalls,
+		TimeFactor:   2,
+		MemFactor:    2,
+	},
+}
+
+// GetLanguage 根据名称获取编程语言，名称为空时使用默认语言
+func GetLanguage(name string) (*Language, bool) {
+	if name == "" {
+		name = DefaultLanguage
+	}
+	lang, ok := Languages[strings.ToLower(name)]
+	return lang, ok
+}
+
+// runArgs 按照运行限制生成运行命令
+func (l *Language) runArgs(limit Limit) []string {
+	if l.MaxMemArg == "" {
+		return l.Run
+	}
+	mem := fmt.Sprintf(l.MaxMemArg, (limit.MaxMem+1023)/1024)
+	return append([]string{l.Run[0], mem}, l.Run[1:]...)
+}
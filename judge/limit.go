@@ -0,0 +1,47 @@
+package judge
+
+// LimitRule 编程语言的时间和内存限制规则，MaxRuntime和MaxMem不为0时直接使用，否则按照倍数计算
+type LimitRule struct {
+	TimeFactor float64 `json:"time_factor"`
+	MemFactor  float64 `json:"mem_factor"`
+	MaxRuntime int     `json:"max_runtime"`
+	MaxMem     int     `json:"max_mem"`
+}
+
+// merge 用o覆盖r，时间和内存分别整体覆盖：o设置了倍数或绝对值时，r中同一项的倍数和绝对值都被替换，
+// 避免更具体的规则中的倍数被更宽泛的规则中的绝对值覆盖
+func (r LimitRule) merge(o *LimitRule) LimitRule {
+	if o == nil {
+		return r
+	}
+	if o.TimeFactor != 0 || o.MaxRuntime != 0 {
+		r.TimeFactor, r.MaxRuntime = o.TimeFactor, o.MaxRuntime
+	}
+	if o.MemFactor != 0 || o.MaxMem != 0 {
+		r.MemFactor, r.MaxMem = o.MemFactor, o.MaxMem
+	}
+	return r
+}
+
+// EffectiveLimit 计算问题在某种编程语言下的运行限制。
+// 规则依次覆盖编程语言的默认倍数，一般先传入全局规则，再传入问题的规则
+func EffectiveLimit(base Limit, lang *Language, rules ...*LimitRule) Limit {
+	r := LimitRule{TimeFactor: lang.TimeFactor, MemFactor: lang.MemFactor}
+	for _, rule := range rules {
+		r = r.merge(rule)
+	}
+	limit := base
+	if r.TimeFactor > 0 {
+		limit.MaxRuntime = int(float64(base.MaxRuntime) * r.TimeFactor)
+	}
+	if r.MemFactor > 0 {
+		limit.MaxMem = int(float64(base.MaxMem) * r.MemFactor)
+	}
+	if r.MaxRuntime != 0 {
+		limit.MaxRuntime = r.MaxRuntime
+	}
+	if r.MaxMem != 0 {
+		limit.MaxMem = r.MaxMem
+	}
+	return limit
+}
@@ -0,0 +1,277 @@
+package judge
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	// 编译的最长时间
+	compileTimeout = time.Second * 30
+	// 墙上时间限制为时间限制的倍数
+	wallTimeFactor = 2
+	// 判断结果中保留的标准错误的最大长度
+	stderrLimit = 1024
+	// 编译错误信息的最大长度
+	compileMsgLimit = 4096
+)
+
+// LocalJudge 在本机上编译并运行代码，用户程序运行时设置rlimit资源限制
+type LocalJudge struct {
+	// InitPath 用户程序的启动进程(cmd/judge-init)的路径
+	InitPath string
+	// CgroupRoot cgroup v2的目录，不为空时每次运行都在其下创建子cgroup，通过memory.max和pids.max限制用户程序
+	CgroupRoot string
+	// MaxProcs 用户程序的进程（线程）数限制，为0时不限制
+	MaxProcs int
+	// Sandbox 是否在新的user/pid/mount/network namespace中运行用户程序，并使用seccomp限制系统调用，
+	// 编译同样在隔离环境中进行
+	Sandbox bool
+	// CompileCacheDir 编译器的缓存目录（如go的构建缓存），为空时使用临时目录下的judge-compile-cache
+	CompileCacheDir string
+}
+
+// Validate 检查配置，隔离环境中允许创建进程，不限制进程数时fork炸弹不受限制
+func (j *LocalJudge) Validate() error {
+	if j.Sandbox && j.MaxProcs <= 0 {
+		return errors.New("judge sandbox requires MaxProcs > 0 to limit processes (RLIMIT_NPROC and cgroup pids.max)")
+	}
+	return nil
+}
+
+// Compile 在代码所在目录中执行编译命令
+func (j *LocalJudge) Compile(ctx context.Context, lang *Language, path string) (*Program, error) {
+	prog := &Program{
+		Dir:      filepath.Dir(path),
+		Language: lang,
+	}
+	if len(lang.Compile) == 0 {
+		return prog, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, compileTimeout)
+	defer cancel()
+	sb, err := j.compileSandbox(ctx, prog)
+	if err != nil {
+		return nil, err
+	}
+	defer sb.remove()
+	// 编译错误的信息可能很长，只保留开头部分
+	out := &headWriter{limit: compileMsgLimit}
+	sb.cmd.Stdout = out
+	sb.cmd.Stderr = out
+	if err := sb.start(); err != nil {
+		return nil, err
+	}
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			sb.kill()
+		case <-done:
+		}
+	}()
+	if err := sb.wait(); err != nil {
+		log.Println("compile err:", err)
+	}
+	close(done)
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return nil, &CompileError{Msg: "编译超时"}
+	}
+	if strings.HasPrefix(out.String(), initFailedPrefix) {
+		return nil, errors.New(strings.TrimSpace(out.String()))
+	}
+	if sb.oomKilled() {
+		return nil, &CompileError{Msg: "编译超内存"}
+	}
+	if reason := sb.exitReason(); reason != "" {
+		if out.buf.Len() == 0 {
+			return nil, &CompileError{Msg: "编译失败:" + reason}
+		}
+		return nil, &CompileError{Msg: out.String()}
+	}
+	return prog, nil
+}
+
+func (j *LocalJudge) Run(ctx context.Context, prog *Program, tc *TestCase, limit Limit, cmp Compare) *CaseResult {
+	res := &CaseResult{Identity: tc.Identity}
+	// 以CPU时间判断是否超时，墙上时间只用来结束一直没有退出的程序（如等待输入）
+	runCtx, cancel := context.WithTimeout(ctx, time.Millisecond*time.Duration(limit.MaxRuntime*wallTimeFactor))
+	defer cancel()
+	sb, err := j.sandbox(runCtx, prog, limit)
+	if err != nil {
+		log.Println("create sandbox err:", err)
+		res.Status = StatusSystemError
+		res.Msg = "系统错误"
+		return res
+	}
+	defer sb.remove()
+	cmd := sb.cmd
+	// 输出超限或与标准输出不一致时结束用户程序，只有评测程序需要完整的输出
+	out := &outputWriter{limit: limit.maxOutput(), kill: cancel}
+	switch {
+	case cmp.Interactor != nil:
+	case cmp.Checker != nil, cmp.Mode == compareNone:
+		out.buf = new(bytes.Buffer)
+	default:
+		expected, err := tc.openOutput()
+		if err != nil {
+			log.Println("open test case output err:", err)
+			res.Status = StatusSystemError
+			res.Msg = "系统错误"
+			return res
+		}
+		defer expected.Close()
+		out.cmp = cmp.stream(expected)
+	}
+	stderr := &headWriter{limit: stderrLimit}
+	var it *interactor
+	if cmp.Interactor != nil {
+		// 交互题的输入输出都连接到交互程序
+		it, err = j.interact(runCtx, cmp.Interactor, tc, limit, cmd)
+		if err != nil {
+			log.Println("start interactor err:", err)
+			res.Status = StatusSystemError
+			res.Msg = "系统错误"
+			return res
+		}
+		defer it.remove()
+	} else {
+		// 输入为文件时exec直接将其作为用户程序的标准输入，不需要复制
+		input, err := tc.openInput()
+		if err != nil {
+			log.Println("open test case input err:", err)
+			res.Status = StatusSystemError
+			res.Msg = "系统错误"
+			return res
+		}
+		defer input.Close()
+		cmd.Stdin = input
+		cmd.Stdout = out
+	}
+	cmd.Stderr = stderr
+
+	// 根据测试的输入案例进行运行拿到输出结果和标准输出结果是否匹配
+	err = sb.start()
+	if err == nil {
+		if it != nil {
+			it.started()
+		}
+		// 超时或被取消时结束整个进程组，避免子进程持有输出管道导致Wait无法返回
+		done := make(chan struct{})
+		go func() {
+			select {
+			case <-runCtx.Done():
+				sb.kill()
+			case <-done:
+			}
+		}()
+		err = sb.wait()
+		close(done)
+	}
+	if err != nil {
+		log.Println(err, stderr.String())
+	}
+	res.Stderr = stderr.String()
+	if cmp.Mode == compareNone {
+		res.Stdout = strings.ToValidUTF8(out.buf.String(), "")
+	}
+	res.Time, res.Mem = sb.usage()
+	if sb.oomKilled() {
+		res.Status = StatusMemoryLimit
+		res.Msg = "运行超内存"
+		return res
+	}
+	// 使用了不允许的系统调用
+	if sb.restricted() {
+		res.Status = StatusRestrictedSyscall
+		res.Msg = "运行错误，使用了不允许的系统调用"
+		return res
+	}
+	// 输出超限或与标准输出不一致时用户程序被提前结束，没有启动进程的报告，在超时和运行错误之前判断
+	if out.exceeded {
+		res.Status = StatusOutputLimit
+		res.Msg = "输出超限"
+		return res
+	}
+	if out.mismatch() {
+		res.Status = StatusWrongAnswer
+		res.Msg = "答案错误"
+		return res
+	}
+	// 运行超时情况
+	if errors.Is(runCtx.Err(), context.DeadlineExceeded) || res.Time > limit.MaxRuntime {
+		res.Status = StatusTimeLimit
+		res.Msg = "运行超时"
+		return res
+	}
+	// 运行超内存情况，包括超过运行参数中的内存限制后退出
+	if res.Mem > limit.MaxMem || prog.Language.OOMExitCode != 0 && sb.exitCode() == prog.Language.OOMExitCode {
+		res.Status = StatusMemoryLimit
+		res.Msg = "运行超内存"
+		return res
+	}
+	// 启动进程出错
+	if strings.HasPrefix(stderr.String(), initFailedPrefix) {
+		log.Println("judge init err:", stderr.String())
+		res.Status = StatusSystemError
+		res.Msg = "系统错误:" + strings.TrimSpace(res.Stderr)
+		return res
+	}
+	// 非0退出码或被信号终止，交互题中交互程序先退出导致的SIGPIPE由交互程序判断
+	if reason := sb.exitReason(); reason != "" && !(it != nil && reason == syscall.SIGPIPE.String()) {
+		res.Status = StatusRuntimeError
+		res.Msg = "运行错误:" + reason
+		return res
+	}
+	// 交互题由交互程序判断，上传了评测程序时由评测程序判断，否则按照比较方式判断
+	var ok bool
+	var msg string
+	switch {
+	case it != nil:
+		ok, msg, err = it.wait(runCtx)
+	case cmp.Checker != nil:
+		ok, msg, err = j.check(ctx, cmp.Checker, tc, out.buf.String())
+	case cmp.Mode == compareNone:
+		ok = true
+	default:
+		ok = out.cmp.equal()
+	}
+	// 交互程序等待用户程序时超时，同样判断为超时
+	if errors.Is(err, errInteractorTimeout) {
+		res.Status = StatusTimeLimit
+		res.Msg = "运行超时:交互程序没有在时间限制内结束"
+		return res
+	}
+	if err != nil {
+		log.Println("run checker err:", err)
+		res.Status = StatusSystemError
+		res.Msg = "系统错误"
+		return res
+	}
+	// 答案错误情况
+	if !ok {
+		res.Status = StatusWrongAnswer
+		res.Msg = "答案错误"
+		if msg != "" {
+			res.Msg += ":" + msg
+		}
+		return res
+	}
+	res.Status = StatusAccepted
+	res.Msg = "答案正确"
+	return res
+}
+
+// truncate 截取s的前n个字节，去掉截断产生的不完整字符
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return strings.ToValidUTF8(s[:n], "")
+}
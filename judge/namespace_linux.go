@@ -0,0 +1,214 @@
+package judge
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// 用户程序所在目录在新的根目录中的位置
+const sandboxDir = "/sandbox"
+
+// 编译器缓存目录在新的根目录中的位置
+const sandboxCacheDir = "/cache"
+
+// 以只读方式挂载到新的根目录中的目录，运行编译后的程序和解释器需要
+var sandboxReadOnlyDirs = []string{"/bin", "/lib", "/lib64", "/usr", "/etc"}
+
+// 编译时以只读方式挂载的目录，只包含工具链，不挂载整个/etc，编译错误信息中不会出现本机的文件内容。
+// /etc/alternatives和/etc/java-*是javac的符号链接和配置，支持通配符
+var compileReadOnlyDirs = []string{"/bin", "/lib", "/lib64", "/usr", "/etc/ld.so.cache", "/etc/alternatives", "/etc/java-*"}
+
+// 挂载到新的根目录中的设备
+var sandboxDevices = []string{"/dev/null", "/dev/zero", "/dev/random", "/dev/urandom"}
+
+// 用户程序运行时的用户，判题服务以root运行时映射为nobody
+const sandboxNobody = 65534
+
+// sandboxMount 用户程序的文件系统：只读的最小根目录，用户程序所在目录挂载到/sandbox
+type sandboxMount struct {
+	Root     string   `json:"root"`     // 新的根目录
+	Dir      string   `json:"dir"`      // 用户程序所在目录
+	Writable bool     `json:"writable"` // 用户程序所在目录是否可写，编译时需要写入编译结果
+	Dirs     []string `json:"dirs"`     // 只读挂载的目录
+	Cache    string   `json:"cache"`    // 可写的编译器缓存目录，挂载到/cache，为空时不挂载
+}
+
+func (m *sandboxMount) apply() error {
+	// 挂载不传播到宿主机
+	if err := unix.Mount("", "/", "", unix.MS_REC|unix.MS_PRIVATE, ""); err != nil {
+		return fmt.Errorf("mount private: %v", err)
+	}
+	if err := unix.Mount("tmpfs", m.Root, "tmpfs", unix.MS_NOSUID|unix.MS_NODEV, "size=1m,mode=755"); err != nil {
+		return fmt.Errorf("mount root: %v", err)
+	}
+	dirs := make([]string, 0, len(m.Dirs))
+	for _, pattern := range m.Dirs {
+		matches, _ := filepath.Glob(pattern)
+		dirs = append(dirs, matches...)
+	}
+	for _, dir := range dirs {
+		fi, err := os.Lstat(dir)
+		if err != nil {
+			continue
+		}
+		if fi.Mode()&os.ModeSymlink != 0 {
+			// 如/lib -> usr/lib
+			link, err := os.Readlink(dir)
+			if err != nil {
+				return err
+			}
+			if err := os.MkdirAll(filepath.Dir(filepath.Join(m.Root, dir)), 0755); err != nil {
+				return err
+			}
+			if err := os.Symlink(link, filepath.Join(m.Root, dir)); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := bindMount(dir, filepath.Join(m.Root, dir), true); err != nil {
+			return err
+		}
+	}
+	if err := bindMount(m.Dir, filepath.Join(m.Root, sandboxDir), !m.Writable); err != nil {
+		return err
+	}
+	if m.Cache != "" {
+		if err := bindMount(m.Cache, filepath.Join(m.Root, sandboxCacheDir), false); err != nil {
+			return err
+		}
+	}
+	if err := os.Mkdir(filepath.Join(m.Root, "dev"), 0755); err != nil {
+		return err
+	}
+	for _, dev := range sandboxDevices {
+		if err := bindMount(dev, filepath.Join(m.Root, dev), false); err != nil {
+			return err
+		}
+	}
+	tmp := filepath.Join(m.Root, "tmp")
+	if err := os.Mkdir(tmp, 0777); err != nil {
+		return err
+	}
+	if err := unix.Mount("tmpfs", tmp, "tmpfs", unix.MS_NOSUID|unix.MS_NODEV|unix.MS_NOEXEC, "size=16m,mode=1777"); err != nil {
+		return fmt.Errorf("mount tmp: %v", err)
+	}
+	proc := filepath.Join(m.Root, "proc")
+	if err := os.Mkdir(proc, 0555); err != nil {
+		return err
+	}
+	// 容器中/proc被部分遮盖时无法挂载，忽略错误
+	unix.Mount("proc", proc, "proc", unix.MS_NOSUID|unix.MS_NODEV|unix.MS_NOEXEC, "")
+
+	// 切换根目录，并卸载原来的根目录
+	old := filepath.Join(m.Root, ".old")
+	if err := os.Mkdir(old, 0700); err != nil {
+		return err
+	}
+	if err := unix.PivotRoot(m.Root, old); err != nil {
+		return fmt.Errorf("pivot root: %v", err)
+	}
+	if err := unix.Chdir("/"); err != nil {
+		return err
+	}
+	if err := unix.Unmount("/.old", unix.MNT_DETACH); err != nil {
+		return fmt.Errorf("unmount old root: %v", err)
+	}
+	if err := os.Remove("/.old"); err != nil {
+		return err
+	}
+	if err := unix.Mount("", "/", "", unix.MS_BIND|unix.MS_REMOUNT|unix.MS_RDONLY|unix.MS_NOSUID|unix.MS_NODEV, ""); err != nil {
+		return fmt.Errorf("remount root: %v", err)
+	}
+	unix.Sethostname([]byte("judge"))
+	return unix.Chdir(sandboxDir)
+}
+
+// bindMount 将src挂载到dst，dst不存在时创建
+func bindMount(src, dst string, readOnly bool) error {
+	fi, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if fi.IsDir() {
+		err = os.MkdirAll(dst, 0755)
+	} else if err = os.MkdirAll(filepath.Dir(dst), 0755); err == nil {
+		var f *os.File
+		f, err = os.Create(dst)
+		if err == nil {
+			f.Close()
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if err := unix.Mount(src, dst, "", unix.MS_BIND|unix.MS_REC, ""); err != nil {
+		return fmt.Errorf("bind %s: %v", src, err)
+	}
+	flags := uintptr(unix.MS_BIND | unix.MS_REMOUNT | unix.MS_NOSUID)
+	if readOnly {
+		flags |= unix.MS_RDONLY
+	}
+	// user namespace中重新挂载时需要保留原挂载点被锁定的选项，statfs的ST_*与MS_*取值相同
+	var st unix.Statfs_t
+	if err := unix.Statfs(dst, &st); err == nil {
+		flags |= uintptr(st.Flags) & (unix.MS_RDONLY | unix.MS_NODEV | unix.MS_NOEXEC |
+			unix.MS_NOATIME | unix.MS_NODIRATIME | unix.MS_RELATIME)
+	}
+	if err := unix.Mount("", dst, "", flags, ""); err != nil {
+		return fmt.Errorf("remount %s: %v", src, err)
+	}
+	return nil
+}
+
+// dropCapabilities 清空capability边界集合，用户程序即使以namespace中的root运行也没有任何capability
+func dropCapabilities() error {
+	for c := 0; c <= unix.CAP_LAST_CAP; c++ {
+		if err := unix.Prctl(unix.PR_CAPBSET_DROP, uintptr(c), 0, 0, 0); err != nil && err != unix.EINVAL {
+			return fmt.Errorf("drop capability %d: %v", c, err)
+		}
+	}
+	return nil
+}
+
+// sandboxUser 用户程序运行时的用户
+type sandboxUser struct {
+	Uid int `json:"uid"`
+	Gid int `json:"gid"`
+}
+
+// newSandboxUser 在新的user/pid/mount/network等namespace中启动用户程序。
+// 启动进程在namespace中为root以完成挂载；判题服务以root运行时，执行用户程序前切换为nobody，
+// 否则只能映射判题服务自身的用户
+func newSandboxUser() (*sandboxUser, *syscall.SysProcAttr) {
+	attr := &syscall.SysProcAttr{
+		Cloneflags: syscall.CLONE_NEWUSER | syscall.CLONE_NEWPID | syscall.CLONE_NEWNS |
+			syscall.CLONE_NEWNET | syscall.CLONE_NEWIPC | syscall.CLONE_NEWUTS,
+		UidMappings: []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getuid(), Size: 1}},
+		GidMappings: []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getgid(), Size: 1}},
+		Pdeathsig:   syscall.SIGKILL,
+	}
+	if os.Getuid() != 0 {
+		return nil, attr
+	}
+	attr.UidMappings = append(attr.UidMappings, syscall.SysProcIDMap{ContainerID: sandboxNobody, HostID: sandboxNobody, Size: 1})
+	attr.GidMappings = append(attr.GidMappings, syscall.SysProcIDMap{ContainerID: sandboxNobody, HostID: sandboxNobody, Size: 1})
+	attr.GidMappingsEnableSetgroups = true
+	return &sandboxUser{Uid: sandboxNobody, Gid: sandboxNobody}, attr
+}
+
+func (u *sandboxUser) apply() error {
+	if err := syscall.Setgroups(nil); err != nil {
+		return fmt.Errorf("setgroups: %v", err)
+	}
+	if err := syscall.Setgid(u.Gid); err != nil {
+		return fmt.Errorf("setgid: %v", err)
+	}
+	if err := syscall.Setuid(u.Uid); err != nil {
+		return fmt.Errorf("setuid: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package judge
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+)
+
+// 未设置输出限制时标准输出的最大长度(KB)
+const defaultMaxOutput = 64 * 1024
+
+var (
+	errOutputLimit = errors.New("output limit exceeded")
+	errMismatch    = errors.New("output mismatch")
+)
+
+// maxOutput 标准输出的最大长度(B)
+func (l Limit) maxOutput() int {
+	if l.MaxOutput <= 0 {
+		return defaultMaxOutput * 1024
+	}
+	return l.MaxOutput * 1024
+}
+
+// outputWriter 接收用户程序的标准输出，超过limit或与标准输出不一致时调用kill并返回错误
+// buf不为nil时保存完整的输出，cmp不为nil时边输出边比较
+// 不嵌入bytes.Buffer，避免io.Copy使用ReadFrom绕过长度限制
+type outputWriter struct {
+	limit    int
+	n        int
+	kill     func()
+	exceeded bool
+	buf      *bytes.Buffer
+	cmp      *streamCompare
+}
+
+func (w *outputWriter) Write(p []byte) (int, error) {
+	if w.n+len(p) > w.limit {
+		w.exceeded = true
+		w.kill()
+		return 0, errOutputLimit
+	}
+	w.n += len(p)
+	if w.buf != nil {
+		w.buf.Write(p)
+	}
+	if w.cmp != nil {
+		w.cmp.Write(p)
+		if w.cmp.failed {
+			w.kill()
+			return 0, errMismatch
+		}
+	}
+	return len(p), nil
+}
+
+// mismatch 输出是否已经与标准输出不一致
+func (w *outputWriter) mismatch() bool {
+	return w.cmp != nil && w.cmp.failed
+}
+
+// headWriter 只保存开头limit字节的输出，其余丢弃
+type headWriter struct {
+	buf   bytes.Buffer
+	limit int
+}
+
+func (w *headWriter) Write(p []byte) (int, error) {
+	if n := w.limit - w.buf.Len(); n > 0 {
+		if len(p) > n {
+			w.buf.Write(p[:n])
+		} else {
+			w.buf.Write(p)
+		}
+	}
+	return len(p), nil
+}
+
+// String 保存的输出，去掉截断产生的不完整字符
+func (w *headWriter) String() string {
+	return strings.ToValidUTF8(w.buf.String(), "")
+}
@@ -0,0 +1,52 @@
+package judge
+
+import "time"
+
+const (
+	// 写入文件的大小限制(B)
+	fileSizeLimit = 16 << 20
+	// 打开文件数限制
+	noFileLimit = 64
+	// 编译器的内存限制(B)
+	compileMaxMem = 2 << 30
+	// 编译时写入文件的大小限制(B)
+	compileFileSizeLimit = 64 << 20
+	// 启动进程出错时写入标准错误的前缀，用来区分用户程序自身的错误
+	initFailedPrefix = "judge init err:"
+)
+
+// Rlimit 用户程序的资源限制，为0时不限制
+type Rlimit struct {
+	CPU          uint64 `json:"cpu"`           // CPU时间(s)
+	AddressSpace uint64 `json:"address_space"` // 虚拟内存(B)
+	FileSize     uint64 `json:"file_size"`     // 写入文件的大小(B)
+	NoFile       uint64 `json:"no_file"`       // 打开文件数
+	NProc        uint64 `json:"nproc"`         // 进程（线程）数
+}
+
+// rlimit 根据运行限制和编程语言计算用户程序的资源限制
+func (j *LocalJudge) rlimit(limit Limit, lang *Language) Rlimit {
+	r := Rlimit{
+		// 向上取整后多给1s，超时由CPU时间判断
+		CPU:      uint64((limit.MaxRuntime+999)/1000 + 1),
+		FileSize: fileSizeLimit,
+		NoFile:   noFileLimit,
+		NProc:    uint64(j.MaxProcs),
+	}
+	if lang.AddressSpace >= 0 {
+		r.AddressSpace = uint64(limit.MaxMem)*1024 + uint64(lang.AddressSpace)
+	}
+	return r
+}
+
+// compileRlimit 编译代码时的资源限制，编译器会创建多个进程，进程数只通过cgroup限制
+func compileRlimit(lang *Language) Rlimit {
+	r := Rlimit{
+		CPU:      uint64(compileTimeout/time.Second) + 1,
+		FileSize: compileFileSizeLimit,
+	}
+	if lang.AddressSpace >= 0 {
+		r.AddressSpace = compileMaxMem + uint64(lang.AddressSpace)
+	}
+	return r
+}
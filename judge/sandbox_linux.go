@@ -0,0 +1,438 @@
+package judge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// 启动进程初始化失败时的退出码
+	initFailedCode = 125
+	// 启动进程向判题服务报告运行结果的文件描述符
+	initReportFd = 3
+	// 标记由启动进程创建、负责执行用户程序的子进程
+	initChildEnv = "JUDGE_INIT_CHILD=1"
+)
+
+// 用户程序运行时的环境变量，设置HOME避免解释器通过NSS查询用户信息（需要创建socket）
+var sandboxEnv = []string{"PATH=/usr/local/bin:/usr/bin:/bin", "HOME=/tmp"}
+
+// sandboxConfig 传递给子进程的配置
+type sandboxConfig struct {
+	Rlimit Rlimit         `json:"rlimit"`
+	Cgroup string         `json:"cgroup"`
+	Mount  *sandboxMount  `json:"mount"`
+	User   *sandboxUser   `json:"user"`
+	Filter *sandboxFilter `json:"filter"`
+	// Env 执行程序时的环境变量，为空时使用sandboxEnv
+	Env []string `json:"env"`
+}
+
+// initReport 启动进程报告的用户程序运行结果
+type initReport struct {
+	Status syscall.WaitStatus `json:"status"`
+	Time   int                `json:"time"` // CPU时间(ms)
+	Mem    int                `json:"mem"`  // 内存峰值(KB)
+}
+
+// Init 用户程序的启动进程(cmd/judge-init)，参数为：配置 用户程序 [用户程序的参数...]
+// 启动进程创建子进程，子进程设置资源限制和隔离环境后执行用户程序，启动进程等待其退出并报告运行结果。
+// 子进程由体积很小的启动进程创建，ru_maxrss不会计入判题服务自身的内存
+func Init() {
+	if len(os.Args) < 3 {
+		initFailed(errors.New("usage: judge-init config program [args...]"))
+	}
+	cfg := new(sandboxConfig)
+	if err := json.Unmarshal([]byte(os.Args[1]), cfg); err != nil {
+		initFailed(err)
+	}
+	for _, env := range os.Environ() {
+		if env == initChildEnv {
+			initChild(cfg)
+		}
+	}
+	// 用户程序不能继承报告结果的管道
+	syscall.CloseOnExec(initReportFd)
+	report := os.NewFile(initReportFd, "report")
+	if cfg.Cgroup != "" {
+		// 加入cgroup后再创建子进程，用户程序创建的进程也会在该cgroup中
+		err := os.WriteFile(filepath.Join(cfg.Cgroup, "cgroup.procs"), []byte(strconv.Itoa(os.Getpid())), 0644)
+		if err != nil {
+			initFailed(err)
+		}
+	}
+	self, err := os.Executable()
+	if err != nil {
+		initFailed(err)
+	}
+	cmd := exec.Command(self, os.Args[1:]...)
+	cmd.Args[0] = os.Args[0]
+	cmd.Env = append(os.Environ(), initChildEnv)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		initFailed(err)
+	}
+	cmd.Wait()
+	res := initReport{Status: cmd.ProcessState.Sys().(syscall.WaitStatus)}
+	if ru, ok := cmd.ProcessState.SysUsage().(*syscall.Rusage); ok {
+		res.Time = int((cmd.ProcessState.UserTime() + cmd.ProcessState.SystemTime()) / time.Millisecond)
+		// linux下ru_maxrss的单位为KB
+		res.Mem = int(ru.Maxrss)
+	}
+	if err := json.NewEncoder(report).Encode(res); err != nil {
+		initFailed(err)
+	}
+	os.Exit(0)
+}
+
+// initChild 设置资源限制和隔离环境后执行用户程序，不会返回
+func initChild(cfg *sandboxConfig) {
+	// seccomp只作用于当前线程，需要在同一个线程中执行用户程序
+	runtime.LockOSThread()
+	if cfg.Mount != nil {
+		if err := cfg.Mount.apply(); err != nil {
+			initFailed(err)
+		}
+	}
+	if cfg.Mount != nil {
+		if err := dropCapabilities(); err != nil {
+			initFailed(err)
+		}
+	}
+	if cfg.User != nil {
+		if err := cfg.User.apply(); err != nil {
+			initFailed(err)
+		}
+	}
+	if cfg.Filter != nil {
+		if err := cfg.Filter.apply(); err != nil {
+			initFailed(err)
+		}
+	}
+	// 在新的根目录中按照PATH查找解释器等命令
+	path, err := exec.LookPath(os.Args[2])
+	if err != nil {
+		initFailed(err)
+	}
+	// 虚拟内存限制可能比启动进程自身需要的更小，在执行用户程序之前才设置
+	if err := setRlimit(cfg.Rlimit); err != nil {
+		initFailed(err)
+	}
+	env := sandboxEnv
+	if cfg.Env != nil {
+		env = cfg.Env
+	}
+	err = syscall.Exec(path, os.Args[2:], env)
+	initFailed(err)
+}
+
+func initFailed(err error) {
+	fmt.Fprintln(os.Stderr, initFailedPrefix, err)
+	os.Exit(initFailedCode)
+}
+
+func setRlimit(r Rlimit) error {
+	limits := []struct {
+		resource int
+		value    uint64
+	}{
+		{unix.RLIMIT_CPU, r.CPU},
+		{unix.RLIMIT_AS, r.AddressSpace},
+		{unix.RLIMIT_FSIZE, r.FileSize},
+		{unix.RLIMIT_NOFILE, r.NoFile},
+		{unix.RLIMIT_NPROC, r.NProc},
+	}
+	for _, l := range limits {
+		if l.value == 0 {
+			continue
+		}
+		cur := l.value
+		max := l.value
+		if l.resource == unix.RLIMIT_CPU {
+			// 超过软限制时收到SIGXCPU，硬限制再多给1s用于SIGKILL
+			max++
+		}
+		// 使用prlimit64，seccomp白名单中没有setrlimit
+		if err := unix.Prlimit(0, l.resource, &unix.Rlimit{Cur: cur, Max: max}, nil); err != nil {
+			return fmt.Errorf("setrlimit %d: %v", l.resource, err)
+		}
+	}
+	return nil
+}
+
+// sandbox 一次运行用户程序的环境
+type sandbox struct {
+	cmd    *exec.Cmd
+	cgroup *cgroup
+	root   string
+	// 读取启动进程报告的运行结果
+	reportR, reportW *os.File
+	report           *initReport
+}
+
+// initPath 返回启动进程的绝对路径
+func (j *LocalJudge) initPath() (string, error) {
+	initPath, err := filepath.Abs(j.InitPath)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(initPath); err != nil {
+		return "", fmt.Errorf("judge init not found, build it with: go build -o %s ./cmd/judge-init", j.InitPath)
+	}
+	return initPath, nil
+}
+
+// sandbox 创建运行用户程序的环境，通过启动进程设置资源限制和隔离环境
+func (j *LocalJudge) sandbox(ctx context.Context, prog *Program, limit Limit) (*sandbox, error) {
+	if err := j.Validate(); err != nil {
+		return nil, err
+	}
+	initPath, err := j.initPath()
+	if err != nil {
+		return nil, err
+	}
+	sb := new(sandbox)
+	cfg := &sandboxConfig{Rlimit: j.rlimit(limit, prog.Language)}
+	if j.CgroupRoot != "" {
+		sb.cgroup, err = newCgroup(j.CgroupRoot, int64(limit.MaxMem)*1024, int64(j.MaxProcs))
+		if err != nil {
+			return nil, err
+		}
+		cfg.Cgroup = sb.cgroup.path
+	}
+	// 用户程序在新的进程组中运行，结束时连同创建的子进程一起结束
+	attr := &syscall.SysProcAttr{}
+	if j.Sandbox {
+		// 新的根目录在用户程序的mount namespace中挂载为tmpfs，运行结束后删除
+		sb.root, err = os.MkdirTemp("", "judge-root-")
+		if err != nil {
+			sb.remove()
+			return nil, err
+		}
+		cfg.Mount = &sandboxMount{
+			Root: sb.root,
+			Dir:  prog.Dir,
+			Dirs: sandboxReadOnlyDirs,
+		}
+		cfg.User, attr = newSandboxUser()
+		cfg.Filter = &sandboxFilter{Syscalls: prog.Language.Syscalls}
+	}
+	if err := sb.command(ctx, initPath, cfg, attr, prog.Dir, prog.Language.runArgs(limit)); err != nil {
+		sb.remove()
+		return nil, err
+	}
+	// 隔离环境中启动进程会切换到/sandbox
+	sb.cmd.Env = sandboxEnv
+	return sb, nil
+}
+
+// compileSandbox 创建编译代码的环境。开启隔离环境时编译器在新的namespace中运行，只能访问只读的工具链、
+// 可写的代码目录和缓存目录，没有网络；编译器需要写入代码目录，不切换为nobody，执行前同样清空capability
+func (j *LocalJudge) compileSandbox(ctx context.Context, prog *Program) (*sandbox, error) {
+	initPath, err := j.initPath()
+	if err != nil {
+		return nil, err
+	}
+	cacheDir, err := j.compileCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	sb := new(sandbox)
+	cfg := &sandboxConfig{Rlimit: compileRlimit(prog.Language), Env: compileEnv(cacheDir, os.TempDir())}
+	if j.CgroupRoot != "" {
+		sb.cgroup, err = newCgroup(j.CgroupRoot, compileMaxMem, int64(j.MaxProcs))
+		if err != nil {
+			return nil, err
+		}
+		cfg.Cgroup = sb.cgroup.path
+	}
+	attr := &syscall.SysProcAttr{}
+	if j.Sandbox {
+		sb.root, err = os.MkdirTemp("", "judge-root-")
+		if err != nil {
+			sb.remove()
+			return nil, err
+		}
+		cfg.Mount = &sandboxMount{
+			Root:     sb.root,
+			Dir:      prog.Dir,
+			Writable: true,
+			Dirs:     compileReadOnlyDirs,
+			Cache:    cacheDir,
+		}
+		_, attr = newSandboxUser()
+		// 临时文件写入代码目录，/tmp的大小不够编译较大的程序
+		cfg.Env = compileEnv(sandboxCacheDir, sandboxDir)
+	}
+	if err := sb.command(ctx, initPath, cfg, attr, prog.Dir, prog.Language.Compile); err != nil {
+		sb.remove()
+		return nil, err
+	}
+	return sb, nil
+}
+
+// compileCacheDir 返回编译器缓存目录的绝对路径，不存在时创建
+func (j *LocalJudge) compileCacheDir() (string, error) {
+	dir := j.CompileCacheDir
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), "judge-compile-cache")
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	return dir, os.MkdirAll(dir, 0755)
+}
+
+// compileEnv 编译器的环境变量，不继承判题服务的环境变量；/usr/local/go/bin为go的默认安装位置，
+// go等编译器的缓存写入XDG_CACHE_HOME
+func compileEnv(cacheDir, tmpDir string) []string {
+	return []string{
+		"PATH=/usr/local/go/bin:/usr/local/bin:/usr/bin:/bin",
+		"HOME=/tmp",
+		"TMPDIR=" + tmpDir,
+		"XDG_CACHE_HOME=" + cacheDir,
+	}
+}
+
+// command 创建通过启动进程在dir中执行args的命令
+func (sb *sandbox) command(ctx context.Context, initPath string, cfg *sandboxConfig, attr *syscall.SysProcAttr, dir string, args []string) error {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	sb.reportR, sb.reportW, err = os.Pipe()
+	if err != nil {
+		return err
+	}
+	sb.cmd = exec.CommandContext(ctx, initPath, append([]string{string(data)}, args...)...)
+	sb.cmd.Dir = dir
+	sb.cmd.Env = cfg.Env
+	sb.cmd.ExtraFiles = []*os.File{sb.reportW}
+	attr.Setpgid = true
+	sb.cmd.SysProcAttr = attr
+	return nil
+}
+
+// start 启动用户程序
+func (sb *sandbox) start() error {
+	err := sb.cmd.Start()
+	// 只由启动进程持有写入端，启动进程退出后可以读到EOF
+	sb.reportW.Close()
+	return err
+}
+
+// wait 等待用户程序退出并读取启动进程报告的运行结果
+func (sb *sandbox) wait() error {
+	err := sb.cmd.Wait()
+	data, _ := io.ReadAll(sb.reportR)
+	if len(data) == 0 {
+		if err == nil {
+			err = errors.New("judge init exited without report")
+		}
+		return err
+	}
+	report := new(initReport)
+	if err := json.Unmarshal(data, report); err != nil {
+		return err
+	}
+	sb.report = report
+	return nil
+}
+
+// usage CPU时间(ms)和内存峰值(KB)，启动进程被结束时没有报告，使用cgroup统计的CPU时间和内存峰值，
+// 没有使用cgroup时无法得到CPU时间，返回0，不使用墙上时间代替
+func (sb *sandbox) usage() (int, int) {
+	if sb.report == nil {
+		if sb.cgroup == nil {
+			return 0, 0
+		}
+		return sb.cgroup.cpuTime(), sb.peak()
+	}
+	mem := sb.report.Mem
+	if peak := sb.peak(); peak > mem {
+		mem = peak
+	}
+	return sb.report.Time, mem
+}
+
+// peak 内存使用峰值(KB)，没有使用cgroup时返回0
+func (sb *sandbox) peak() int {
+	if sb.cgroup == nil {
+		return 0
+	}
+	return sb.cgroup.peak()
+}
+
+// oomKilled 是否因为超过cgroup的内存限制被杀死
+func (sb *sandbox) oomKilled() bool {
+	return sb.cgroup != nil && sb.cgroup.oomKilled()
+}
+
+// restricted 是否因为使用了不允许的系统调用被杀死
+func (sb *sandbox) restricted() bool {
+	if sb.report == nil {
+		return false
+	}
+	status := sb.report.Status
+	return status.Signaled() && status.Signal() == syscall.SIGSYS
+}
+
+// exitCode 用户程序的退出码，被信号终止或没有报告时为-1
+func (sb *sandbox) exitCode() int {
+	if sb.report == nil || !sb.report.Status.Exited() {
+		return -1
+	}
+	return sb.report.Status.ExitStatus()
+}
+
+// exitReason 用户程序非正常退出的原因，正常退出时为空
+func (sb *sandbox) exitReason() string {
+	if sb.report == nil {
+		return "killed"
+	}
+	status := sb.report.Status
+	if status.Signaled() {
+		return status.Signal().String()
+	}
+	if status.ExitStatus() != 0 {
+		return "退出码 " + strconv.Itoa(status.ExitStatus())
+	}
+	return ""
+}
+
+// kill 结束用户程序所在的进程组
+func (sb *sandbox) kill() {
+	if sb.cmd == nil || sb.cmd.Process == nil {
+		return
+	}
+	syscall.Kill(-sb.cmd.Process.Pid, syscall.SIGKILL)
+}
+
+// remove 清理运行环境，结束仍在运行的子进程
+func (sb *sandbox) remove() {
+	sb.kill()
+	sb.cgroup.remove()
+	if sb.reportR != nil {
+		sb.reportR.Close()
+		sb.reportW.Close()
+	}
+	if sb.root != "" {
+		// 挂载点随mount namespace一起销毁，这里只剩下空目录
+		os.Remove(sb.root)
+	}
+}
@@ -0,0 +1,99 @@
+//go:build !linux
+
+package judge
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+// Init 非linux平台不设置资源限制，直接执行用户程序
+func Init() {
+	if len(os.Args) < 3 {
+		fmt.Fprintln(os.Stderr, initFailedPrefix, "missing program")
+		os.Exit(125)
+	}
+	cmd := exec.Command(os.Args[2], os.Args[3:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil && cmd.ProcessState == nil {
+		fmt.Fprintln(os.Stderr, initFailedPrefix, err)
+		os.Exit(125)
+	}
+	os.Exit(cmd.ProcessState.ExitCode())
+}
+
+// 非linux平台不使用seccomp
+var javaSyscalls []uintptr
+
+// sandbox 非linux平台直接运行用户程序
+type sandbox struct {
+	cmd *exec.Cmd
+}
+
+func (j *LocalJudge) sandbox(ctx context.Context, prog *Program, limit Limit) (*sandbox, error) {
+	run := prog.Language.runArgs(limit)
+	cmd := exec.CommandContext(ctx, run[0], run[1:]...)
+	cmd.Dir = prog.Dir
+	return &sandbox{cmd: cmd}, nil
+}
+
+func (j *LocalJudge) compileSandbox(ctx context.Context, prog *Program) (*sandbox, error) {
+	cmd := exec.CommandContext(ctx, prog.Language.Compile[0], prog.Language.Compile[1:]...)
+	cmd.Dir = prog.Dir
+	return &sandbox{cmd: cmd}, nil
+}
+
+func (sb *sandbox) start() error { return sb.cmd.Start() }
+
+func (sb *sandbox) wait() error { return sb.cmd.Wait() }
+
+// usage 非linux平台无法获取子进程的内存峰值
+func (sb *sandbox) usage() (int, int) {
+	if sb.cmd.ProcessState == nil {
+		return 0, 0
+	}
+	return int((sb.cmd.ProcessState.UserTime() + sb.cmd.ProcessState.SystemTime()) / time.Millisecond), 0
+}
+
+func (sb *sandbox) peak() int { return 0 }
+
+func (sb *sandbox) oomKilled() bool { return false }
+
+func (sb *sandbox) restricted() bool { return false }
+
+func (sb *sandbox) exitCode() int {
+	if sb.cmd.ProcessState == nil {
+		return -1
+	}
+	return sb.cmd.ProcessState.ExitCode()
+}
+
+func (sb *sandbox) exitReason() string {
+	state := sb.cmd.ProcessState
+	if state == nil {
+		return "killed"
+	}
+	if state.Success() {
+		return ""
+	}
+	if state.ExitCode() == -1 {
+		return state.String()
+	}
+	return "退出码 " + strconv.Itoa(state.ExitCode())
+}
+
+func (sb *sandbox) kill() {
+	if sb.cmd.Process != nil {
+		sb.cmd.Process.Kill()
+	}
+}
+
+func (sb *sandbox) remove() {
+	sb.kill()
+}
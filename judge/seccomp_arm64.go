@@ -0,0 +1,9 @@
+//go:build linux && arm64
+
+package judge
+
+import "golang.org/x/sys/unix"
+
+const auditArch = unix.AUDIT_ARCH_AARCH64
+
+var archSyscalls = []uintptr{unix.SYS_FSTATAT}
@@ -0,0 +1,126 @@
+package judge
+
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// seccomp过滤器的返回值
+const (
+	seccompRetKillProcess = 0x80000000
+	seccompRetErrno       = 0x00050000
+	seccompRetAllow       = 0x7fff0000
+)
+
+// seccomp_data中各字段的偏移
+const (
+	seccompDataNr   = 0
+	seccompDataArch = 4
+	seccompDataArg0 = 16
+)
+
+// 不允许用户程序通过clone创建新的namespace
+const cloneNamespaceFlags = unix.CLONE_NEWNS | unix.CLONE_NEWUTS | unix.CLONE_NEWIPC |
+	unix.CLONE_NEWUSER | unix.CLONE_NEWPID | unix.CLONE_NEWNET | unix.CLONE_NEWCGROUP
+
+// 允许用户程序使用的系统调用，覆盖编译型语言和常见解释器运行时所需
+var sandboxSyscalls = []uintptr{
+	// 读写
+	unix.SYS_READ, unix.SYS_WRITE, unix.SYS_READV, unix.SYS_WRITEV,
+	unix.SYS_PREAD64, unix.SYS_PWRITE64, unix.SYS_LSEEK, unix.SYS_CLOSE,
+	unix.SYS_OPENAT, unix.SYS_FSTAT, unix.SYS_STATX,
+	unix.SYS_FSTATFS, unix.SYS_STATFS, unix.SYS_READLINKAT, unix.SYS_FACCESSAT,
+	unix.SYS_FACCESSAT2, unix.SYS_GETDENTS64, unix.SYS_FCNTL, unix.SYS_IOCTL,
+	unix.SYS_DUP, unix.SYS_DUP3, unix.SYS_PIPE2, unix.SYS_GETCWD,
+	unix.SYS_PPOLL, unix.SYS_PSELECT6, unix.SYS_EPOLL_CREATE1, unix.SYS_EPOLL_CTL,
+	unix.SYS_EPOLL_PWAIT, unix.SYS_EVENTFD2,
+	// 内存
+	unix.SYS_BRK, unix.SYS_MMAP, unix.SYS_MUNMAP, unix.SYS_MREMAP,
+	unix.SYS_MPROTECT, unix.SYS_MADVISE, unix.SYS_MINCORE, unix.SYS_MSYNC,
+	unix.SYS_MEMBARRIER,
+	// 信号
+	unix.SYS_RT_SIGACTION, unix.SYS_RT_SIGPROCMASK, unix.SYS_RT_SIGRETURN,
+	unix.SYS_RT_SIGTIMEDWAIT, unix.SYS_SIGALTSTACK, unix.SYS_TGKILL, unix.SYS_TKILL,
+	unix.SYS_RESTART_SYSCALL,
+	// 线程和进程
+	unix.SYS_EXECVE, unix.SYS_EXIT, unix.SYS_EXIT_GROUP, unix.SYS_FUTEX,
+	unix.SYS_GETTID, unix.SYS_GETPID, unix.SYS_GETPPID, unix.SYS_SET_TID_ADDRESS,
+	unix.SYS_SET_ROBUST_LIST, unix.SYS_GET_ROBUST_LIST, unix.SYS_RSEQ,
+	unix.SYS_SCHED_YIELD, unix.SYS_SCHED_GETAFFINITY, unix.SYS_SCHED_GETPARAM,
+	unix.SYS_SCHED_GETSCHEDULER, unix.SYS_PRCTL, unix.SYS_PRLIMIT64,
+	unix.SYS_GETRLIMIT, unix.SYS_GETRUSAGE, unix.SYS_WAIT4,
+	unix.SYS_GETUID, unix.SYS_GETEUID, unix.SYS_GETGID, unix.SYS_GETEGID,
+	unix.SYS_GETGROUPS,
+	// 时间和系统信息
+	unix.SYS_CLOCK_GETTIME, unix.SYS_CLOCK_GETRES, unix.SYS_CLOCK_NANOSLEEP,
+	unix.SYS_GETTIMEOFDAY, unix.SYS_NANOSLEEP, unix.SYS_TIMES, unix.SYS_UNAME,
+	unix.SYS_SYSINFO, unix.SYS_GETRANDOM,
+}
+
+// java虚拟机额外需要的系统调用
+var javaSyscalls = []uintptr{
+	unix.SYS_MKDIRAT, unix.SYS_UNLINKAT, unix.SYS_FTRUNCATE, unix.SYS_FCHDIR,
+	unix.SYS_CHDIR, unix.SYS_FLOCK, unix.SYS_SCHED_SETAFFINITY, unix.SYS_GETSID,
+}
+
+// sandboxFilter 用户程序的seccomp系统调用白名单，使用其他系统调用时进程被SIGSYS杀死
+type sandboxFilter struct {
+	Syscalls []uintptr `json:"syscalls"` // 编程语言额外需要的系统调用
+}
+
+func (f *sandboxFilter) apply() error {
+	if auditArch == 0 {
+		return errors.New("seccomp is not supported on this architecture")
+	}
+	syscalls := append(append(sandboxSyscalls, archSyscalls...), f.Syscalls...)
+	filter := seccompFilter(syscalls)
+	prog := unix.SockFprog{
+		Len:    uint16(len(filter)),
+		Filter: &filter[0],
+	}
+	// 不允许用户程序获得新的权限，非特权进程设置seccomp的前提
+	if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+		return fmt.Errorf("set no new privs: %v", err)
+	}
+	if err := unix.Prctl(unix.PR_SET_SECCOMP, unix.SECCOMP_MODE_FILTER, uintptr(unsafe.Pointer(&prog)), 0, 0); err != nil {
+		return fmt.Errorf("set seccomp: %v", err)
+	}
+	return nil
+}
+
+// seccompFilter 生成只允许指定系统调用的BPF程序
+func seccompFilter(syscalls []uintptr) []unix.SockFilter {
+	stmt := func(code uint16, k uint32) unix.SockFilter {
+		return unix.SockFilter{Code: code, K: k}
+	}
+	jump := func(code uint16, k uint32, jt, jf uint8) unix.SockFilter {
+		return unix.SockFilter{Code: code, Jt: jt, Jf: jf, K: k}
+	}
+	filter := []unix.SockFilter{
+		// 只允许当前架构的系统调用
+		stmt(unix.BPF_LD|unix.BPF_W|unix.BPF_ABS, seccompDataArch),
+		jump(unix.BPF_JMP|unix.BPF_JEQ|unix.BPF_K, auditArch, 1, 0),
+		stmt(unix.BPF_RET|unix.BPF_K, seccompRetKillProcess),
+		stmt(unix.BPF_LD|unix.BPF_W|unix.BPF_ABS, seccompDataNr),
+		// clone3的参数无法检查，返回ENOSYS让运行时退回到clone
+		jump(unix.BPF_JMP|unix.BPF_JEQ|unix.BPF_K, unix.SYS_CLONE3, 0, 1),
+		stmt(unix.BPF_RET|unix.BPF_K, seccompRetErrno|uint32(unix.ENOSYS)),
+		// clone只允许创建线程和进程，不允许创建namespace
+		jump(unix.BPF_JMP|unix.BPF_JEQ|unix.BPF_K, unix.SYS_CLONE, 0, 4),
+		stmt(unix.BPF_LD|unix.BPF_W|unix.BPF_ABS, seccompDataArg0),
+		jump(unix.BPF_JMP|unix.BPF_JSET|unix.BPF_K, cloneNamespaceFlags, 0, 1),
+		stmt(unix.BPF_RET|unix.BPF_K, seccompRetKillProcess),
+		stmt(unix.BPF_RET|unix.BPF_K, seccompRetAllow),
+	}
+	// 此时累加器中为系统调用号
+	for _, nr := range syscalls {
+		filter = append(filter,
+			jump(unix.BPF_JMP|unix.BPF_JEQ|unix.BPF_K, uint32(nr), 0, 1),
+			stmt(unix.BPF_RET|unix.BPF_K, seccompRetAllow),
+		)
+	}
+	return append(filter, stmt(unix.BPF_RET|unix.BPF_K, seccompRetKillProcess))
+}
@@ -0,0 +1,8 @@
+//go:build linux && !amd64 && !arm64
+
+package judge
+
+// 其他架构不支持seccomp过滤
+const auditArch = 0
+
+var archSyscalls = []uintptr{}
@@ -0,0 +1,60 @@
+package judge
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// openInput 打开测试用例的输入
+func (tc *TestCase) openInput() (io.ReadCloser, error) {
+	return open(tc.InputFile, tc.Input)
+}
+
+// openOutput 打开测试用例的标准输出
+func (tc *TestCase) openOutput() (io.ReadCloser, error) {
+	return open(tc.OutputFile, tc.Output)
+}
+
+func open(file, data string) (io.ReadCloser, error) {
+	if file != "" {
+		return os.Open(file)
+	}
+	return io.NopCloser(strings.NewReader(data)), nil
+}
+
+// caseFiles 返回临时目录以及输入文件、标准输出文件和data依次写入的文件路径
+// 测试用例的数据已经在文件中时直接使用该文件
+func caseFiles(tc *TestCase, data ...string) (string, []string, error) {
+	dir, err := os.MkdirTemp("", "judge-check-")
+	if err != nil {
+		return "", nil, err
+	}
+	files := make([]string, 0, len(data)+2)
+	write := func(file, d string) error {
+		if file == "" {
+			file = filepath.Join(dir, strconv.Itoa(len(files)))
+			if err := os.WriteFile(file, []byte(d), 0644); err != nil {
+				return err
+			}
+		}
+		files = append(files, file)
+		return nil
+	}
+	err = write(tc.InputFile, tc.Input)
+	if err == nil {
+		err = write(tc.OutputFile, tc.Output)
+	}
+	for _, d := range data {
+		if err == nil {
+			err = write("", d)
+		}
+	}
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", nil, err
+	}
+	return dir, files, nil
+}
@@ -1,10 +1,30 @@
 package main
 
 import (
+	"context"
+	"gin_gorm_oj/define"
+	"gin_gorm_oj/dispatch"
+	"gin_gorm_oj/judge"
 	"gin_gorm_oj/router"
+	"gin_gorm_oj/worker"
+	"log"
 )
 
 func main() {
+	// 保存判题节点返回的判断结果
+	dispatch.Start(define.JudgeResultWorkerNum)
+	// 在判题服务中运行判题节点，其他机器上的判题节点通过cmd/judge-worker启动
+	if define.JudgeWorkerNum > 0 {
+		if lj, ok := judge.Default.(*judge.LocalJudge); ok {
+			if err := lj.Validate(); err != nil {
+				log.Fatalln(err)
+			}
+		}
+		w := worker.New(dispatch.Queue, judge.Default, define.JudgeWorkerNum, worker.AvailableLanguages())
+		go w.Run(context.Background())
+	} else {
+		log.Println("define.JudgeWorkerNum is 0, submissions are judged by judge-worker")
+	}
 	r := router.Router()
 	r.Run(":8081")
 }
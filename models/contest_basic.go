@@ -0,0 +1,66 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ContestBasic 比赛，报名的用户在StartAt到EndAt之间对比赛中问题的提交计入比赛
+type ContestBasic struct {
+	gorm.Model
+	Identity        string            `gorm:"column:identity;type:varchar(36);" json:"identity"`                                 // 比赛的唯一标识
+	Title           string            `gorm:"column:title;type:varchar(255);" json:"title"`                                      // 比赛标题
+	Content         string            `gorm:"column:content;type:text;" json:"content"`                                          // 比赛说明
+	StartAt         time.Time         `gorm:"column:start_at;type:datetime;" json:"start_at"`                                    // 开始时间
+	EndAt           time.Time         `gorm:"column:end_at;type:datetime;" json:"end_at"`                                        // 结束时间
+	ContestProblems []*ContestProblem `gorm:"foreignKey:contest_identity;references:identity" json:"contest_problems,omitempty"` // 比赛中的问题，按照编号排序
+	UserNum         int64             `gorm:"-" json:"user_num"`                                                                 // 报名人数
+}
+
+func (table *ContestBasic) TableName() string {
+	return "contest_basic"
+}
+
+// Started 比赛是否已经开始
+func (table *ContestBasic) Started(now time.Time) bool {
+	return !now.Before(table.StartAt)
+}
+
+// Ended 比赛是否已经结束
+func (table *ContestBasic) Ended(now time.Time) bool {
+	return !now.Before(table.EndAt)
+}
+
+func GetContestList(keyword string) *gorm.DB {
+	return DB.Model(new(ContestBasic)).Where("title like ?", "%"+keyword+"%")
+}
+
+// hiddenProblems 未开始的比赛中的问题
+func hiddenProblems(now time.Time) *gorm.DB {
+	return DB.Model(new(ContestProblem)).Select("contest_problem.problem_identity").
+		Joins("JOIN contest_basic cb ON cb.identity = contest_problem.contest_identity AND cb.deleted_at IS NULL").
+		Where("cb.start_at > ?", now)
+}
+
+// IsProblemHidden 问题是否属于未开始的比赛，未开始的比赛中的问题不公开
+func IsProblemHidden(problemIdentity string) (bool, error) {
+	var cnt int64
+	err := hiddenProblems(time.Now()).Where("contest_problem.problem_identity = ?", problemIdentity).Count(&cnt).Error
+	return cnt > 0, err
+}
+
+// GetContestIdentity 用户报名的、正在进行的、包含该问题的比赛，有多个时为最早开始的比赛，没有时返回空字符串
+func GetContestIdentity(problemIdentity, userIdentity string, now time.Time) (string, error) {
+	identities := make([]string, 0)
+	err := DB.Model(new(ContestBasic)).
+		Joins("JOIN contest_problem cp ON cp.contest_identity = contest_basic.identity AND cp.deleted_at IS NULL").
+		Joins("JOIN contest_user cu ON cu.contest_identity = contest_basic.identity AND cu.deleted_at IS NULL").
+		Where("cp.problem_identity = ? AND cu.user_identity = ?", problemIdentity, userIdentity).
+		Where("contest_basic.start_at <= ? AND contest_basic.end_at > ?", now, now).
+		Order("contest_basic.start_at").Limit(1).Pluck("contest_basic.identity", &identities).Error
+	if err != nil || len(identities) == 0 {
+		return "", err
+	}
+	return identities[0], nil
+}
@@ -0,0 +1,16 @@
+package models
+
+import "gorm.io/gorm"
+
+// ContestProblem 比赛中的问题，Letter为问题在比赛中的编号
+type ContestProblem struct {
+	gorm.Model
+	ContestIdentity string        `gorm:"column:contest_identity;type:varchar(36);" json:"contest_identity"`
+	ProblemIdentity string        `gorm:"column:problem_identity;type:varchar(36);" json:"problem_identity"`
+	ProblemBasic    *ProblemBasic `gorm:"foreignKey:identity;references:problem_identity" json:"problem_basic,omitempty"`
+	Letter          string        `gorm:"column:letter;type:varchar(2);" json:"letter"` // 编号，如A、B
+}
+
+func (table *ContestProblem) TableName() string {
+	return "contest_problem"
+}
@@ -0,0 +1,15 @@
+package models
+
+import "gorm.io/gorm"
+
+// ContestUser 报名比赛的用户
+type ContestUser struct {
+	gorm.Model
+	ContestIdentity string     `gorm:"column:contest_identity;type:varchar(36);" json:"contest_identity"`
+	UserIdentity    string     `gorm:"column:user_identity;type:varchar(36);" json:"user_identity"`
+	UserBasic       *UserBasic `gorm:"foreignKey:identity;references:user_identity" json:"user_basic,omitempty"`
+}
+
+func (table *ContestUser) TableName() string {
+	return "contest_user"
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"gin_gorm_oj/define"
 	"log"
 
 	"github.com/go-redis/redis/v8"
@@ -23,7 +24,7 @@ func Init() *gorm.DB {
 
 func InitRedisDB() *redis.Client {
 	return redis.NewClient(&redis.Options{
-		Addr:     "127.0.0.1:6379",
+		Addr:     define.RedisAddr,
 		Password: "",
 		DB:       0,
 	})
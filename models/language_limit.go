@@ -0,0 +1,65 @@
+package models
+
+import (
+	"gin_gorm_oj/judge"
+
+	"gorm.io/gorm"
+)
+
+// LanguageLimit 编程语言的时间和内存限制规则，ProblemIdentity为空时为全局默认规则
+type LanguageLimit struct {
+	gorm.Model
+	ProblemIdentity string  `gorm:"column:problem_identity;type:varchar(36);" json:"problem_identity"`
+	Language        string  `gorm:"column:language;type:varchar(20);" json:"language"`
+	TimeFactor      float64 `gorm:"column:time_factor;type:double;" json:"time_factor"` // 时间限制的倍数
+	MemFactor       float64 `gorm:"column:mem_factor;type:double;" json:"mem_factor"`   // 内存限制的倍数
+	MaxRuntime      int     `gorm:"column:max_runtime;type:int;" json:"max_runtime"`    // 时间限制，不为0时覆盖倍数
+	MaxMem          int     `gorm:"column:max_mem;type:int;" json:"max_mem"`            // 内存限制，不为0时覆盖倍数
+}
+
+func (table *LanguageLimit) TableName() string {
+	return "language_limit"
+}
+
+// GetLanguageLimits 获取全局规则和问题的规则
+func GetLanguageLimits(problemIdentity string) ([]*LanguageLimit, error) {
+	list := make([]*LanguageLimit, 0)
+	err := DB.Where("problem_identity = '' OR problem_identity = ?", problemIdentity).Find(&list).Error
+	return list, err
+}
+
+// EffectiveLimit 计算问题在某种编程语言下的运行限制，问题的规则优先于全局规则
+func EffectiveLimit(pb *ProblemBasic, lang *judge.Language, rules []*LanguageLimit) judge.Limit {
+	var global, problem *judge.LimitRule
+	for _, r := range rules {
+		if r.Language != lang.Name {
+			continue
+		}
+		rule := &judge.LimitRule{
+			TimeFactor: r.TimeFactor,
+			MemFactor:  r.MemFactor,
+			MaxRuntime: r.MaxRuntime,
+			MaxMem:     r.MaxMem,
+		}
+		if r.ProblemIdentity == "" {
+			global = rule
+		} else {
+			problem = rule
+		}
+	}
+	base := judge.Limit{
+		MaxRuntime: pb.MaxRuntime,
+		MaxMem:     pb.MaxMem,
+		MaxOutput:  pb.MaxOutput,
+	}
+	return judge.EffectiveLimit(base, lang, global, problem)
+}
+
+// EffectiveLimits 计算问题在所有编程语言下的运行限制
+func EffectiveLimits(pb *ProblemBasic, rules []*LanguageLimit) map[string]judge.Limit {
+	limits := make(map[string]judge.Limit)
+	for name, lang := range judge.Languages {
+		limits[name] = EffectiveLimit(pb, lang, rules)
+	}
+	return limits
+}
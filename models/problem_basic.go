@@ -1,29 +1,62 @@
 package models
 
 import (
+	"gin_gorm_oj/judge"
+	"time"
+
 	"gorm.io/gorm"
 )
 
 type ProblemBasic struct {
 	gorm.Model
-	Identity          string             `gorm:"column:identity;type:varchar(36);" json:"identity"` // 问题的唯一标识
-	ProblemCategories []*ProblemCategory `gorm:"foreignKey:problem_id;references:id"`
-	Title             string             `gorm:"column:title;type:varchar(255);" json:"title"` // 题目的标题
-	Content           string             `gorm:"column:content;type:text;" json:"content"`     // 题目正文描述
-	MaxMem            int                `gorm:"column:max_mem;type:int;" json:"max_mem"`
-	MaxRuntime        int                `gorm:"column:max_runtime;type:int;" json:"max_runtime"`
-	TestCase          []*TestCase        `gorm:"foreignKey:problem_identity;references:identity"`
-	PassNum           int64              `gorm:"column:pass_num;type:int(11);" json:"pass_num"`     // 通过个数
-	SubmitNum         int64              `gorm:"column:submit_num;type:int(11);" json:"submit_num"` // 提交次数
+	Identity           string                 `gorm:"column:identity;type:varchar(36);" json:"identity"` // 问题的唯一标识
+	ProblemCategories  []*ProblemCategory     `gorm:"foreignKey:problem_id;references:id"`
+	Title              string                 `gorm:"column:title;type:varchar(255);" json:"title"` // 题目的标题
+	Content            string                 `gorm:"column:content;type:text;" json:"content"`     // 题目正文描述
+	MaxMem             int                    `gorm:"column:max_mem;type:int;" json:"max_mem"`
+	MaxRuntime         int                    `gorm:"column:max_runtime;type:int;" json:"max_runtime"`
+	MaxOutput          int                    `gorm:"column:max_output;type:int;" json:"max_output"`                           // 输出限制(KB)，为0时使用默认的输出限制
+	TestCase           []*TestCase            `gorm:"foreignKey:problem_identity;references:identity" json:"-"`                // 测试用例，不在接口中返回，样例通过Samples返回
+	PassNum            int64                  `gorm:"column:pass_num;type:int(11);" json:"pass_num"`                           // 通过个数
+	SubmitNum          int64                  `gorm:"column:submit_num;type:int(11);" json:"submit_num"`                       // 提交次数
+	CompareMode        string                 `gorm:"column:compare_mode;type:varchar(20);" json:"compare_mode"`               // 输出比较方式，为空时逐字节比较
+	AbsEpsilon         float64                `gorm:"column:abs_epsilon;type:double;" json:"abs_epsilon"`                      // 浮点数比较的绝对误差
+	RelEpsilon         float64                `gorm:"column:rel_epsilon;type:double;" json:"rel_epsilon"`                      // 浮点数比较的相对误差
+	CheckerPath        string                 `gorm:"column:checker_path;type:varchar(255);" json:"-"`                         // 评测程序代码路径，为空时按照比较方式判断
+	CheckerLanguage    string                 `gorm:"column:checker_language;type:varchar(20);" json:"checker_language"`       // 评测程序的编程语言
+	InteractorPath     string                 `gorm:"column:interactor_path;type:varchar(255);" json:"-"`                      // 交互程序代码路径，不为空时为交互题
+	InteractorLanguage string                 `gorm:"column:interactor_language;type:varchar(20);" json:"interactor_language"` // 交互程序的编程语言
+	Limits             map[string]judge.Limit `gorm:"-" json:"limits,omitempty"`                                               // 各编程语言下实际的运行限制
+	Samples            []*Sample              `gorm:"-" json:"samples,omitempty"`                                              // 样例的输入输出
 }
 
 func (table *ProblemBasic) TableName() string {
 	return "problem_basic"
 }
 
+// Compare 问题的输出比较方式，评测程序和交互程序的代码随任务发送，由判题节点编译
+func (table *ProblemBasic) Compare() judge.Compare {
+	return judge.Compare{
+		Mode:       table.CompareMode,
+		AbsEpsilon: table.AbsEpsilon,
+		RelEpsilon: table.RelEpsilon,
+	}
+}
+
+// ProgramPath 评测程序(checker)或交互程序(interactor)的代码路径
+func (table *ProblemBasic) ProgramPath(kind string) string {
+	if kind == "interactor" {
+		return table.InteractorPath
+	}
+	return table.CheckerPath
+}
+
 func GetProblemList(keyword string, categoryIdentity string) *gorm.DB {
 	tx := DB.Model(new(ProblemBasic)).Preload("ProblemCategories").Preload("ProblemCategories.CategoryBasic").Where("title like ? OR content like ?", "%"+keyword+"%", "%"+keyword+"%")
 
+	// 不显示未开始的比赛中的问题
+	tx.Where("problem_basic.identity NOT IN (?)", hiddenProblems(time.Now()))
+
 	if categoryIdentity != "" {
 		tx.Joins("RIGHT JOIN problem_category pc on pc.problem_id = problem_basic.id").Where("pc.category_id = (SELECT cb.id FROM category_basic cb WHERE cb.identity = ?)", categoryIdentity)
 	}
@@ -1,16 +1,26 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"gin_gorm_oj/judge"
+
+	"gorm.io/gorm"
+)
 
 type SubmitBasic struct {
 	gorm.Model
-	Identity        string        `gorm:"column:identity;type:varchar(36);" json:"identity"`
-	ProblemIdentity string        `gorm:"column:problem_identity;type:varchar(36);" json:"problem_identity"`
-	ProblemBasic    *ProblemBasic `gorm:"foreignKey:identity;references:problem_identity"`
-	UserIdentity    string        `gorm:"column:user_identity;type:varchar(36);" json:"user_identity"`
-	UserBasic       *UserBasic    `gorm:"foreignKey:identity;references:user_identity"`
-	Path            string        `gorm:"column:path;type:varchar(255);" json:"path"`
-	Status          int           `gorm:"column:status;type:tinyint(1);" json:"tinyint"`
+	Identity        string              `gorm:"column:identity;type:varchar(36);" json:"identity"`
+	ProblemIdentity string              `gorm:"column:problem_identity;type:varchar(36);" json:"problem_identity"`
+	ProblemBasic    *ProblemBasic       `gorm:"foreignKey:identity;references:problem_identity"`
+	UserIdentity    string              `gorm:"column:user_identity;type:varchar(36);" json:"user_identity"`
+	UserBasic       *UserBasic          `gorm:"foreignKey:identity;references:user_identity"`
+	ContestIdentity string              `gorm:"column:contest_identity;type:varchar(36);" json:"contest_identity"` // 比赛进行期间报名的用户的提交所属的比赛
+	Path            string              `gorm:"column:path;type:varchar(255);" json:"path"`
+	Language        string              `gorm:"column:language;type:varchar(20);" json:"language"` // 编程语言
+	Status          int                 `gorm:"column:status;type:tinyint(1);" json:"tinyint"`
+	Msg             string              `gorm:"column:msg;type:text;" json:"msg"`                                             // 判断结果的提示信息，编译错误时为编译器输出
+	RunTime         int                 `gorm:"column:run_time;type:int;" json:"run_time"`                                    // 所有测试用例中最长的CPU时间(ms)
+	RunMem          int                 `gorm:"column:run_mem;type:int;" json:"run_mem"`                                      // 所有测试用例中最大的内存峰值(KB)
+	CaseResults     []*SubmitCaseResult `gorm:"foreignKey:submit_identity;references:identity" json:"case_results,omitempty"` // 每个测试用例的判断结果
 }
 
 func (table *SubmitBasic) TableName() string {
@@ -33,3 +43,24 @@ func GetSubmitList(problemIdentity string, userIdentity string, status int) *gor
 	}
 	return tx
 }
+
+// UpdatePassNum 按照通过的提交重新统计用户和问题的通过个数
+func UpdatePassNum(tx *gorm.DB, userIdentities, problemIdentities []string) error {
+	if len(userIdentities) > 0 {
+		err := tx.Model(new(UserBasic)).Where("identity IN ?", userIdentities).Update("PassNum", gorm.Expr(
+			"(SELECT COUNT(*) FROM submit_basic WHERE submit_basic.user_identity = user_basic.identity AND submit_basic.status = ? AND submit_basic.deleted_at IS NULL)",
+			judge.StatusAccepted)).Error
+		if err != nil {
+			return err
+		}
+	}
+	if len(problemIdentities) > 0 {
+		err := tx.Model(new(ProblemBasic)).Where("identity IN ?", problemIdentities).Update("PassNum", gorm.Expr(
+			"(SELECT COUNT(*) FROM submit_basic WHERE submit_basic.problem_identity = problem_basic.identity AND submit_basic.status = ? AND submit_basic.deleted_at IS NULL)",
+			judge.StatusAccepted)).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
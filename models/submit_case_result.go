@@ -0,0 +1,38 @@
+package models
+
+import "gorm.io/gorm"
+
+// SubmitCaseResult 提交在每个测试用例上的判断结果
+type SubmitCaseResult struct {
+	gorm.Model
+	SubmitIdentity   string `gorm:"column:submit_identity;type:varchar(36);index;" json:"submit_identity"`
+	TestCaseIdentity string `gorm:"column:test_case_identity;type:varchar(36);" json:"test_case_identity"`
+	Status           int    `gorm:"column:status;type:tinyint(1);" json:"status"`
+	Msg              string `gorm:"column:msg;type:text;" json:"msg"`
+	RunTime          int    `gorm:"column:run_time;type:int;" json:"run_time"` // CPU时间(ms)
+	RunMem           int    `gorm:"column:run_mem;type:int;" json:"run_mem"`   // 内存峰值(KB)
+	Stderr           string `gorm:"column:stderr;type:text;" json:"stderr"`    // 标准错误的开头部分
+}
+
+func (table *SubmitCaseResult) TableName() string {
+	return "submit_case_result"
+}
+
+// HideStderr 清空非样例测试用例的标准错误，用户程序可以将隐藏的测试数据输出到标准错误
+func HideStderr(problemIdentity string, results []*SubmitCaseResult) error {
+	samples := make([]string, 0)
+	err := DB.Model(new(TestCase)).Where("problem_identity = ? AND is_sample = ?", problemIdentity, true).Pluck("identity", &samples).Error
+	if err != nil {
+		return err
+	}
+	isSample := make(map[string]bool, len(samples))
+	for _, identity := range samples {
+		isSample[identity] = true
+	}
+	for _, r := range results {
+		if !isSample[r.TestCaseIdentity] {
+			r.Stderr = ""
+		}
+	}
+	return nil
+}
@@ -1,15 +1,142 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"gin_gorm_oj/storage"
+	"io"
+	"log"
+	"strings"
 
+	"gorm.io/gorm"
+)
+
+// TestCase 测试用例，输入输出保存在存储（本地目录或对象存储）中，数据库只保存对象的key、大小和sha256
+// Input和Output为旧版本保存在数据库中的数据，InputKey为空时使用
+// 只有样例(IsSample)的数据通过/problem-detail公开，测试用例的数据不出现在任何接口的返回中
 type TestCase struct {
 	gorm.Model
 	Identity        string `gorm:"column:identity;type:varchar(36);" json:"identity"`
 	ProblemIdentity string `gorm:"column:problem_identity;type:varchar(36);" json:"problem_identity"`
-	Input           string `gorm:"column:input;type:text;" json:"input"`
-	Output          string `gorm:"column:output;type:text;" json:"output"`
+	IsSample        bool   `gorm:"column:is_sample;type:tinyint(1);" json:"is_sample"` // 是否为样例
+	Input           string `gorm:"column:input;type:text;" json:"-"`
+	Output          string `gorm:"column:output;type:text;" json:"-"`
+	InputKey        string `gorm:"column:input_key;type:varchar(255);" json:"-"`
+	InputSize       int64  `gorm:"column:input_size;type:bigint;" json:"input_size"`
+	InputSha256     string `gorm:"column:input_sha256;type:char(64);" json:"input_sha256"`
+	OutputKey       string `gorm:"column:output_key;type:varchar(255);" json:"-"`
+	OutputSize      int64  `gorm:"column:output_size;type:bigint;" json:"output_size"`
+	OutputSha256    string `gorm:"column:output_sha256;type:char(64);" json:"output_sha256"`
 }
 
 func (table *TestCase) TableName() string {
 	return "test_case"
 }
+
+// NewTestCase 创建测试用例并将输入输出保存到存储中
+func NewTestCase(ctx context.Context, problemIdentity, identity, input, output string) (*TestCase, error) {
+	tc := &TestCase{Identity: identity, ProblemIdentity: problemIdentity}
+	err := tc.SaveData(ctx, strings.NewReader(input), int64(len(input)), strings.NewReader(output), int64(len(output)))
+	if err != nil {
+		return nil, err
+	}
+	return tc, nil
+}
+
+// SaveData 将输入输出保存到存储中，key为testcase/问题标识/测试用例标识.in(.out)
+func (table *TestCase) SaveData(ctx context.Context, input io.Reader, inputSize int64, output io.Reader, outputSize int64) error {
+	prefix := "testcase/" + table.ProblemIdentity + "/" + table.Identity
+	sum, err := putData(ctx, prefix+".in", input, inputSize)
+	if err != nil {
+		return err
+	}
+	table.InputKey, table.InputSize, table.InputSha256 = prefix+".in", inputSize, sum
+	sum, err = putData(ctx, prefix+".out", output, outputSize)
+	if err != nil {
+		storage.Default.Delete(ctx, table.InputKey)
+		return err
+	}
+	table.OutputKey, table.OutputSize, table.OutputSha256 = prefix+".out", outputSize, sum
+	table.Input, table.Output = "", ""
+	return nil
+}
+
+// putData 保存对象，返回内容的sha256
+func putData(ctx context.Context, key string, r io.Reader, size int64) (string, error) {
+	h := sha256.New()
+	if err := storage.Default.Put(ctx, key, io.TeeReader(r, h), size); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// DeleteTestCaseData 删除测试用例在存储中的数据，只记录删除失败的对象
+func DeleteTestCaseData(ctx context.Context, tcs []*TestCase) {
+	for _, tc := range tcs {
+		for _, key := range []string{tc.InputKey, tc.OutputKey} {
+			if key == "" {
+				continue
+			}
+			if err := storage.Default.Delete(ctx, key); err != nil {
+				log.Println("delete test case data err:", key, err)
+			}
+		}
+	}
+}
+
+// OpenInput 读取测试用例的输入
+func (table *TestCase) OpenInput(ctx context.Context) (io.ReadCloser, error) {
+	if table.InputKey == "" {
+		return io.NopCloser(strings.NewReader(table.Input)), nil
+	}
+	return storage.Default.Get(ctx, table.InputKey)
+}
+
+// OpenOutput 读取测试用例的标准输出
+func (table *TestCase) OpenOutput(ctx context.Context) (io.ReadCloser, error) {
+	if table.OutputKey == "" {
+		return io.NopCloser(strings.NewReader(table.Output)), nil
+	}
+	return storage.Default.Get(ctx, table.OutputKey)
+}
+
+// Sample 公开的样例数据
+type Sample struct {
+	Input  string `json:"input"`
+	Output string `json:"output"`
+}
+
+// 样例数据返回的最大长度，超过时截断
+const sampleLimit = 64 * 1024
+
+// GetSamples 按顺序读取问题的样例数据
+func GetSamples(ctx context.Context, problemIdentity string) ([]*Sample, error) {
+	tcs := make([]*TestCase, 0)
+	err := DB.Where("problem_identity = ? AND is_sample = ?", problemIdentity, true).Order("id").Find(&tcs).Error
+	if err != nil {
+		return nil, err
+	}
+	samples := make([]*Sample, 0, len(tcs))
+	for _, tc := range tcs {
+		input, err := readSample(tc.OpenInput(ctx))
+		if err != nil {
+			return nil, err
+		}
+		output, err := readSample(tc.OpenOutput(ctx))
+		if err != nil {
+			return nil, err
+		}
+		samples = append(samples, &Sample{Input: input, Output: output})
+	}
+	return samples, nil
+}
+
+func readSample(r io.ReadCloser, err error) (string, error) {
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	data, err := io.ReadAll(io.LimitReader(r, sampleLimit))
+	return strings.ToValidUTF8(string(data), ""), err
+}
@@ -0,0 +1,57 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"gin_gorm_oj/judge"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// 提交的判断进度通过redis发布订阅传递，每个提交一个频道
+const progressChannelPrefix = "submit_progress:"
+
+// 判断进度的类型
+const (
+	EventJudging = "judging" // 判题节点开始判断
+	EventCase    = "case"    // 一个测试用例结束
+	EventResult  = "result"  // 判断结果已经保存
+)
+
+// Event 提交的判断进度
+type Event struct {
+	Type   string            `json:"type"`
+	Total  int               `json:"total"`            // 测试用例数
+	Done   int               `json:"done"`             // 已经结束的测试用例数
+	Index  int               `json:"index"`            // 结束的测试用例的下标
+	Case   *judge.CaseResult `json:"case,omitempty"`   // 结束的测试用例的结果
+	Result *judge.Result     `json:"result,omitempty"` // 最终的判断结果
+}
+
+// Publish 发布提交的判断进度
+func (q *Queue) Publish(ctx context.Context, identity string, e *Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return q.RDB.Publish(ctx, progressChannelPrefix+identity, data).Err()
+}
+
+// Subscribe 订阅提交的判断进度，返回时已经订阅成功，使用后需要关闭
+func (q *Queue) Subscribe(ctx context.Context, identity string) (*redis.PubSub, error) {
+	ps := q.RDB.Subscribe(ctx, progressChannelPrefix+identity)
+	if _, err := ps.Receive(ctx); err != nil {
+		ps.Close()
+		return nil, err
+	}
+	return ps, nil
+}
+
+// ParseEvent 解析订阅收到的判断进度
+func ParseEvent(msg *redis.Message) (*Event, error) {
+	e := new(Event)
+	if err := json.Unmarshal([]byte(msg.Payload), e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
@@ -0,0 +1,300 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"gin_gorm_oj/judge"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	// 判题任务的队列，每种编程语言一个，判题节点只取出自己支持的编程语言的任务
+	jobQueuePrefix = "judge_queue:"
+	// 自定义输入运行任务的队列，判题节点优先处理
+	runQueuePrefix = "run_queue:"
+	// 判题结果的队列，由判题服务保存到数据库
+	resultQueueKey = "judge_result"
+	// 自定义输入运行的结果，判题节点写入后由接口读取
+	runResultPrefix = "run_result:"
+	// 运行结果没有被读取时保留的时间
+	runResultExpire = time.Minute * 5
+	// 判题节点取出的任务在完成前保存在"队列:processing:判题节点"中，判题节点下线后放回原来的队列
+	processingInfix = ":processing:"
+	// 所有队列都为空时在一个队列上阻塞等待的时间，之后重新检查所有队列
+	popWait = time.Second
+)
+
+// 任务的类型
+const (
+	KindSubmit  = "submit"  // 判断提交
+	KindRun     = "run"     // 自定义输入运行
+	KindCompile = "compile" // 编译检查上传的评测程序或交互程序
+)
+
+// Job 判题任务，包含判题需要的全部数据，判题节点不需要访问数据库和判题服务的代码目录
+type Job struct {
+	Kind     string        `json:"kind"`
+	Identity string        `json:"identity"` // 提交或运行的唯一标识
+	Language string        `json:"language"`
+	Code     string        `json:"code"`
+	Limit    judge.Limit   `json:"limit"`
+	Compare  judge.Compare `json:"compare"`
+	// Checker、Interactor为评测程序和交互程序的代码，由判题节点编译并缓存
+	Checker       *Program    `json:"checker,omitempty"`
+	Interactor    *Program    `json:"interactor,omitempty"`
+	TestCases     []*TestCase `json:"test_cases,omitempty"`
+	StopOnFailure bool        `json:"stop_on_failure"`
+	Input         string      `json:"input,omitempty"` // 自定义输入运行的输入
+	// 取出时的原始数据和所在的处理中列表，完成后通过Ack删除
+	raw        string
+	processing string
+}
+
+// Program 评测程序或交互程序的代码
+type Program struct {
+	Language string `json:"language"`
+	Code     string `json:"code"`
+}
+
+// TestCase 测试用例，数据保存在存储中时判题节点按照key和sha256下载，否则使用Input、Output
+type TestCase struct {
+	Identity     string `json:"identity"`
+	InputKey     string `json:"input_key,omitempty"`
+	InputSha256  string `json:"input_sha256,omitempty"`
+	OutputKey    string `json:"output_key,omitempty"`
+	OutputSha256 string `json:"output_sha256,omitempty"`
+	Input        string `json:"input,omitempty"`
+	Output       string `json:"output,omitempty"`
+}
+
+// Result 判题节点返回的判断结果
+type Result struct {
+	Identity string        `json:"identity"`
+	Worker   string        `json:"worker"`
+	Result   *judge.Result `json:"result"`
+}
+
+// Queue 判题服务和判题节点之间通过redis传递任务和结果
+type Queue struct {
+	RDB *redis.Client
+}
+
+// Push 将任务放入对应编程语言的队列，自定义输入运行和编译检查需要等待结果，放入优先处理的队列
+func (q *Queue) Push(ctx context.Context, job *Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	key := jobQueuePrefix + job.Language
+	if job.Kind == KindRun || job.Kind == KindCompile {
+		key = runQueuePrefix + job.Language
+	}
+	return q.RDB.LPush(ctx, key, data).Err()
+}
+
+// Pop 从支持的编程语言的队列中取出一个任务，优先取出自定义输入运行的任务，超时没有取到时返回nil。
+// 任务同时移入worker的处理中列表，完成后需要调用Ack，worker下线时由Requeue放回队列
+func (q *Queue) Pop(ctx context.Context, worker string, languages []string, timeout time.Duration) (*Job, error) {
+	keys := make([]string, 0, len(languages)*2)
+	for _, l := range languages {
+		keys = append(keys, runQueuePrefix+l)
+	}
+	for _, l := range languages {
+		keys = append(keys, jobQueuePrefix+l)
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	deadline := time.Now().Add(timeout)
+	for i := 0; ; i++ {
+		// BLMOVE只能等待一个队列，先按照优先级依次检查所有队列
+		for _, key := range keys {
+			job, err := q.move(ctx, key, worker, 0)
+			if job != nil || err != nil {
+				return job, err
+			}
+		}
+		if time.Until(deadline) <= 0 {
+			return nil, nil
+		}
+		// redis阻塞命令的超时以秒为单位，返回的时间可能比timeout晚不到1s
+		job, err := q.move(ctx, keys[i%len(keys)], worker, popWait)
+		if job != nil || err != nil {
+			return job, err
+		}
+	}
+}
+
+// move 将key中最早放入的任务移入worker的处理中列表，wait为0时不等待
+func (q *Queue) move(ctx context.Context, key, worker string, wait time.Duration) (*Job, error) {
+	processing := key + processingInfix + worker
+	var data string
+	var err error
+	if wait > 0 {
+		data, err = q.RDB.BLMove(ctx, key, processing, "RIGHT", "LEFT", wait).Result()
+	} else {
+		data, err = q.RDB.LMove(ctx, key, processing, "RIGHT", "LEFT").Result()
+	}
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	job := new(Job)
+	if err := json.Unmarshal([]byte(data), job); err != nil {
+		// 无法解析的任务不会被处理，直接删除
+		q.RDB.LRem(ctx, processing, 1, data)
+		return nil, err
+	}
+	job.raw, job.processing = data, processing
+	return job, nil
+}
+
+// Ack 任务已经完成，从处理中列表删除
+func (q *Queue) Ack(ctx context.Context, job *Job) error {
+	if job.processing == "" {
+		return nil
+	}
+	return q.RDB.LRem(ctx, job.processing, 1, job.raw).Err()
+}
+
+// Requeue 将已经下线（心跳过期）的判题节点取出但没有完成的任务放回原来的队列，返回放回的任务数。
+// 判题节点与redis断开超过心跳的有效期时任务可能被判断两次，保存结果时只更新待判断的提交
+func (q *Queue) Requeue(ctx context.Context) (int, error) {
+	cnt := 0
+	for _, prefix := range []string{runQueuePrefix, jobQueuePrefix} {
+		iter := q.RDB.Scan(ctx, 0, prefix+"*"+processingInfix+"*", 100).Iterator()
+		for iter.Next(ctx) {
+			key := iter.Val()
+			_, worker, _ := strings.Cut(key, processingInfix)
+			alive, err := q.RDB.Exists(ctx, workerKeyPrefix+worker).Result()
+			if err != nil {
+				return cnt, err
+			}
+			if alive > 0 {
+				continue
+			}
+			n, err := q.requeue(ctx, key)
+			cnt += n
+			if err != nil {
+				return cnt, err
+			}
+		}
+		if err := iter.Err(); err != nil {
+			return cnt, err
+		}
+	}
+	return cnt, nil
+}
+
+// RequeueWorker 将判题节点上次运行时没有完成的任务放回队列，判题节点以相同的标识重新启动时调用
+func (q *Queue) RequeueWorker(ctx context.Context, worker string) (int, error) {
+	cnt := 0
+	for _, prefix := range []string{runQueuePrefix, jobQueuePrefix} {
+		iter := q.RDB.Scan(ctx, 0, prefix+"*"+processingInfix+worker, 100).Iterator()
+		for iter.Next(ctx) {
+			n, err := q.requeue(ctx, iter.Val())
+			cnt += n
+			if err != nil {
+				return cnt, err
+			}
+		}
+		if err := iter.Err(); err != nil {
+			return cnt, err
+		}
+	}
+	return cnt, nil
+}
+
+// requeue 将处理中列表中的任务移回原来的队列，放在最先被取出的一端
+func (q *Queue) requeue(ctx context.Context, processing string) (int, error) {
+	key, _, _ := strings.Cut(processing, processingInfix)
+	cnt := 0
+	for {
+		err := q.RDB.LMove(ctx, processing, key, "RIGHT", "RIGHT").Err()
+		if err == redis.Nil {
+			return cnt, nil
+		}
+		if err != nil {
+			return cnt, err
+		}
+		cnt++
+	}
+}
+
+// Len 各编程语言等待处理的判题任务数
+func (q *Queue) Len(ctx context.Context, languages []string) (map[string]int64, error) {
+	res := make(map[string]int64)
+	for _, l := range languages {
+		n, err := q.RDB.LLen(ctx, jobQueuePrefix+l).Result()
+		if err != nil {
+			return nil, err
+		}
+		m, err := q.RDB.LLen(ctx, runQueuePrefix+l).Result()
+		if err != nil {
+			return nil, err
+		}
+		res[l] = n + m
+	}
+	return res, nil
+}
+
+// PushResult 返回提交的判断结果
+func (q *Queue) PushResult(ctx context.Context, res *Result) error {
+	data, err := json.Marshal(res)
+	if err != nil {
+		return err
+	}
+	return q.RDB.LPush(ctx, resultQueueKey, data).Err()
+}
+
+// PopResult 取出一个判断结果，超时没有取到时返回nil
+func (q *Queue) PopResult(ctx context.Context, timeout time.Duration) (*Result, error) {
+	res, err := q.RDB.BRPop(ctx, timeout, resultQueueKey).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	r := new(Result)
+	if err := json.Unmarshal([]byte(res[1]), r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// PushRunResult 返回自定义输入运行或编译检查的结果
+func (q *Queue) PushRunResult(ctx context.Context, identity string, res *judge.CaseResult) error {
+	data, err := json.Marshal(res)
+	if err != nil {
+		return err
+	}
+	key := runResultPrefix + identity
+	_, err = q.RDB.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.LPush(ctx, key, data)
+		pipe.Expire(ctx, key, runResultExpire)
+		return nil
+	})
+	return err
+}
+
+// WaitRunResult 等待自定义输入运行或编译检查的结果，超时时返回nil
+func (q *Queue) WaitRunResult(ctx context.Context, identity string, timeout time.Duration) (*judge.CaseResult, error) {
+	res, err := q.RDB.BLPop(ctx, timeout, runResultPrefix+identity).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	cr := new(judge.CaseResult)
+	if err := json.Unmarshal([]byte(res[1]), cr); err != nil {
+		return nil, err
+	}
+	return cr, nil
+}
@@ -0,0 +1,78 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+)
+
+// 判题节点的心跳，过期后视为判题节点已经下线
+const (
+	workerKeyPrefix = "judge_worker:"
+	workerExpire    = time.Second * 15
+	// HeartbeatInterval 判题节点发送心跳的间隔
+	HeartbeatInterval = time.Second * 5
+)
+
+// WorkerInfo 判题节点的状态
+type WorkerInfo struct {
+	ID        string    `json:"id"`
+	Host      string    `json:"host"`
+	Capacity  int       `json:"capacity"` // 同时处理的任务数
+	Busy      int       `json:"busy"`     // 正在处理的任务数
+	Languages []string  `json:"languages"`
+	Started   time.Time `json:"started"`
+	Updated   time.Time `json:"updated"`
+}
+
+// Heartbeat 更新判题节点的状态
+func (q *Queue) Heartbeat(ctx context.Context, w *WorkerInfo) error {
+	w.Updated = time.Now()
+	data, err := json.Marshal(w)
+	if err != nil {
+		return err
+	}
+	return q.RDB.Set(ctx, workerKeyPrefix+w.ID, data, workerExpire).Err()
+}
+
+// RemoveWorker 判题节点退出时删除其状态
+func (q *Queue) RemoveWorker(ctx context.Context, id string) error {
+	return q.RDB.Del(ctx, workerKeyPrefix+id).Err()
+}
+
+// Workers 在线的判题节点，按照ID排序
+func (q *Queue) Workers(ctx context.Context) ([]*WorkerInfo, error) {
+	keys := make([]string, 0)
+	iter := q.RDB.Scan(ctx, 0, workerKeyPrefix+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+	workers := make([]*WorkerInfo, 0, len(keys))
+	if len(keys) == 0 {
+		return workers, nil
+	}
+	values, err := q.RDB.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range values {
+		// 扫描之后过期的节点
+		s, ok := v.(string)
+		if !ok {
+			continue
+		}
+		w := new(WorkerInfo)
+		if err := json.Unmarshal([]byte(s), w); err != nil {
+			continue
+		}
+		workers = append(workers, w)
+	}
+	sort.Slice(workers, func(i, j int) bool {
+		return workers[i].ID < workers[j].ID
+	})
+	return workers, nil
+}
@@ -35,6 +35,13 @@ func Router() *gin.Engine {
 
 	// 提交记录
 	r.GET("/submit-list", service.GetSubmitList)
+	r.GET("/submit-detail", service.GetSubmitDetail)
+	// 提交的判断进度
+	r.GET("/submit-status", service.GetSubmitStatus)
+
+	// 比赛
+	r.GET("/contest-list", service.GetContestList)
+	r.GET("/contest-detail", service.GetContestDetail)
 
 	// 管理员私有方法
 	authAdmin := r.Group("/admin", middlewares.AuthAdminCheck())
@@ -42,6 +49,22 @@ func Router() *gin.Engine {
 	authAdmin.POST("/problem-create", service.ProblemCreate)
 	// 问题修改
 	authAdmin.PUT("/problem-modify", service.ProblemMotify)
+	// 上传测试用例压缩包
+	authAdmin.POST("/problem-test-case-upload", service.ProblemTestCaseUpload)
+	// 导出题目包
+	authAdmin.GET("/problem-export", service.ProblemExport)
+	// 导入题目包
+	authAdmin.POST("/problem-import", service.ProblemImport)
+	// 评测程序
+	authAdmin.POST("/problem-checker", service.ProblemChecker)
+	authAdmin.DELETE("/problem-checker-delete", service.ProblemCheckerDelete)
+	// 交互程序
+	authAdmin.POST("/problem-interactor", service.ProblemInteractor)
+	authAdmin.DELETE("/problem-interactor-delete", service.ProblemInteractorDelete)
+	// 比赛创建
+	authAdmin.POST("/contest-create", service.ContestCreate)
+	// 比赛修改
+	authAdmin.PUT("/contest-modify", service.ContestModify)
 	// 分类列表
 	authAdmin.GET("/category-list", service.GetCategoryList)
 	// 分类创建
@@ -50,11 +73,23 @@ func Router() *gin.Engine {
 	authAdmin.PUT("/category-modify", service.CategoryModify)
 	// 分类删除
 	authAdmin.DELETE("/category-delete", service.CategoryDelete)
+	// 编程语言限制规则
+	authAdmin.GET("/language-limit-list", service.GetLanguageLimitList)
+	authAdmin.PUT("/language-limit-modify", service.LanguageLimitModify)
+	authAdmin.DELETE("/language-limit-delete", service.LanguageLimitDelete)
+	// 重新判断提交
+	authAdmin.POST("/rejudge", service.Rejudge)
+	// 判题节点列表
+	authAdmin.GET("/worker-list", service.GetWorkerList)
 
 	// 用户私有方法
 	authUser := r.Group("/user", middlewares.AuthUserCheck())
 	// 代码提交
 	authUser.POST("/submit", service.Submit)
+	// 使用自定义输入运行代码
+	authUser.POST("/run", service.Run)
+	// 比赛报名
+	authUser.POST("/contest-register", service.ContestRegister)
 
 	return r
 }
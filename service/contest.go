@@ -0,0 +1,348 @@
+package service
+
+import (
+	"errors"
+	"gin_gorm_oj/define"
+	"gin_gorm_oj/helper"
+	"gin_gorm_oj/models"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetContestList
+// @Tags 公共方法
+// @Summary 比赛列表
+// @Param page query int false "请输入当前页面，默认第一页"
+// @Param size query int false "size"
+// @Param keyword query string false "keyword"
+// @Success 200 {string} json "{"code":"200","data":""}"
+// @Router /contest-list [get]
+func GetContestList(ctx *gin.Context) {
+	size, _ := strconv.Atoi(ctx.DefaultQuery("size", define.DefaultSize))
+	page, err := strconv.Atoi(ctx.DefaultQuery("page", define.DefaultPage))
+	if err != nil {
+		log.Println("get contest list page parse error:", err)
+		return
+	}
+	page = (page - 1) * size
+	var count int64
+	keyword := ctx.Query("keyword")
+
+	list := make([]*models.ContestBasic, 0)
+	err = models.GetContestList(keyword).Count(&count).Omit("content").Order("start_at DESC").Offset(page).Limit(size).Find(&list).Error
+	if err != nil {
+		log.Println("get contest list error:", err)
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  "Get contestList Error:" + err.Error(),
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"data": map[string]interface{}{
+			"list":  list,
+			"count": count,
+		},
+	})
+}
+
+// GetContestDetail
+// @Tags 公共方法
+// @Summary 比赛详情
+// @Description 比赛开始后才返回比赛中的问题
+// @Param identity query string true "contest identity"
+// @Success 200 {string} json "{"code":"200","data":""}"
+// @Router /contest-detail [get]
+func GetContestDetail(ctx *gin.Context) {
+	identity := ctx.Query("identity")
+	if identity == "" {
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  "比赛唯一标识不能为空",
+		})
+		return
+	}
+	data := new(models.ContestBasic)
+	err := models.DB.Where("identity = ?", identity).First(data).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			ctx.JSON(http.StatusOK, gin.H{
+				"code": -1,
+				"msg":  "当前比赛不存在",
+			})
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  "Get contestDetail Error:" + err.Error(),
+		})
+		return
+	}
+	if data.Started(time.Now()) {
+		err = models.DB.Where("contest_identity = ?", identity).Preload("ProblemBasic", func(db *gorm.DB) *gorm.DB {
+			return db.Select("identity", "title", "max_runtime", "max_mem", "submit_num", "pass_num")
+		}).Order("letter").Find(&data.ContestProblems).Error
+		if err != nil {
+			ctx.JSON(http.StatusOK, gin.H{
+				"code": -1,
+				"msg":  "Get contestProblem Error:" + err.Error(),
+			})
+			return
+		}
+	}
+	err = models.DB.Model(new(models.ContestUser)).Where("contest_identity = ?", identity).Count(&data.UserNum).Error
+	if err != nil {
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  "Get contestUser Error:" + err.Error(),
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"data": data,
+	})
+}
+
+// ContestCreate
+// @Tags 管理员私有方法
+// @Summary 比赛创建
+// @Param authorization header string true "authorization"
+// @Param title formData string true "title"
+// @Param content formData string false "比赛说明"
+// @Param start_at formData string true "开始时间，如2006-01-02 15:04:05"
+// @Param end_at formData string true "结束时间，如2006-01-02 17:04:05"
+// @Param problem_identities formData []string true "比赛中的问题，按照顺序编号为A、B、C..." collectionFormat(multi)
+// @Success 200 {string} json "{"code":"200","msg":"","data":""}"
+// @Router /admin/contest-create [post]
+func ContestCreate(ctx *gin.Context) {
+	data, problemIdentities, err := contestForm(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  err.Error(),
+		})
+		return
+	}
+	data.Identity = helper.GetUUID()
+	data.ContestProblems = contestProblems(data.Identity, problemIdentities)
+	err = models.DB.Create(data).Error
+	if err != nil {
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  "contest create err:" + err.Error(),
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"data": map[string]interface{}{
+			"identity": data.Identity,
+		},
+	})
+}
+
+// ContestModify
+// @Tags 管理员私有方法
+// @Summary 比赛修改
+// @Description 比赛中的问题替换为problem_identities，已经计入比赛的提交不变
+// @Param authorization header string true "authorization"
+// @Param identity formData string true "identity"
+// @Param title formData string true "title"
+// @Param content formData string false "比赛说明"
+// @Param start_at formData string true "开始时间，如2006-01-02 15:04:05"
+// @Param end_at formData string true "结束时间，如2006-01-02 17:04:05"
+// @Param problem_identities formData []string true "比赛中的问题，按照顺序编号为A、B、C..." collectionFormat(multi)
+// @Success 200 {string} json "{"code":"200","msg":"","data":""}"
+// @Router /admin/contest-modify [put]
+func ContestModify(ctx *gin.Context) {
+	identity := ctx.PostForm("identity")
+	if identity == "" {
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  "参数不能为空",
+		})
+		return
+	}
+	data, problemIdentities, err := contestForm(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  err.Error(),
+		})
+		return
+	}
+	if err := models.DB.Transaction(func(tx *gorm.DB) error {
+		// 比赛基础信息保存，说明可以修改为空
+		result := tx.Model(new(models.ContestBasic)).Where("identity = ?", identity).Updates(map[string]interface{}{
+			"title":    data.Title,
+			"content":  data.Content,
+			"start_at": data.StartAt,
+			"end_at":   data.EndAt,
+		})
+		if result.Error != nil {
+			return errors.New("contest modify err:" + result.Error.Error())
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("当前比赛不存在")
+		}
+		// 比赛中的问题
+		err := tx.Where("contest_identity = ?", identity).Delete(new(models.ContestProblem)).Error
+		if err != nil {
+			return errors.New("contestproblem delete err:" + err.Error())
+		}
+		err = tx.Create(contestProblems(identity, problemIdentities)).Error
+		if err != nil {
+			return errors.New("contestproblem create err:" + err.Error())
+		}
+		return nil
+	}); err != nil {
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  err.Error(),
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "比赛修改成功",
+	})
+}
+
+// ContestRegister
+// @Tags 用户私有方法
+// @Summary 比赛报名
+// @Description 比赛结束前可以报名，报名后在比赛进行期间对比赛中问题的提交计入比赛
+// @Param authorization header string true "authorization"
+// @Param identity formData string true "contest identity"
+// @Success 200 {string} json "{"code":"200","msg":"","data":""}"
+// @Router /user/contest-register [post]
+func ContestRegister(ctx *gin.Context) {
+	identity := ctx.PostForm("identity")
+	if identity == "" {
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  "比赛唯一标识不能为空",
+		})
+		return
+	}
+	contest := new(models.ContestBasic)
+	err := models.DB.Where("identity = ?", identity).First(contest).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			ctx.JSON(http.StatusOK, gin.H{
+				"code": -1,
+				"msg":  "当前比赛不存在",
+			})
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  "Get contestDetail Error:" + err.Error(),
+		})
+		return
+	}
+	if contest.Ended(time.Now()) {
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  "比赛已经结束",
+		})
+		return
+	}
+	u, _ := ctx.Get("user")
+	userClaim := u.(*helper.UserClaims)
+	var cnt int64
+	err = models.DB.Model(new(models.ContestUser)).Where("contest_identity = ? AND user_identity = ?", identity, userClaim.Identity).Count(&cnt).Error
+	if err != nil {
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  "Get contestUser Error:" + err.Error(),
+		})
+		return
+	}
+	if cnt > 0 {
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  "已经报名",
+		})
+		return
+	}
+	err = models.DB.Create(&models.ContestUser{
+		ContestIdentity: identity,
+		UserIdentity:    userClaim.Identity,
+	}).Error
+	if err != nil {
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  "contest register err:" + err.Error(),
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "报名成功",
+	})
+}
+
+// contestForm 解析并检查比赛的标题、时间和问题
+func contestForm(ctx *gin.Context) (*models.ContestBasic, []string, error) {
+	title := ctx.PostForm("title")
+	problemIdentities := ctx.PostFormArray("problem_identities")
+	if title == "" || len(problemIdentities) == 0 {
+		return nil, nil, errors.New("参数不能为空")
+	}
+	startAt, err := time.ParseInLocation(define.TimeLayout, ctx.PostForm("start_at"), time.Local)
+	if err != nil {
+		return nil, nil, errors.New("开始时间格式错误")
+	}
+	endAt, err := time.ParseInLocation(define.TimeLayout, ctx.PostForm("end_at"), time.Local)
+	if err != nil {
+		return nil, nil, errors.New("结束时间格式错误")
+	}
+	if !endAt.After(startAt) {
+		return nil, nil, errors.New("结束时间必须晚于开始时间")
+	}
+	if len(problemIdentities) > define.ContestMaxProblems {
+		return nil, nil, errors.New("比赛中的问题不能超过" + strconv.Itoa(define.ContestMaxProblems) + "个")
+	}
+	seen := make(map[string]bool)
+	for _, identity := range problemIdentities {
+		if seen[identity] {
+			return nil, nil, errors.New("比赛中的问题重复:" + identity)
+		}
+		seen[identity] = true
+	}
+	var cnt int64
+	err = models.DB.Model(new(models.ProblemBasic)).Where("identity IN ?", problemIdentities).Count(&cnt).Error
+	if err != nil {
+		return nil, nil, errors.New("get problem err:" + err.Error())
+	}
+	if int(cnt) != len(problemIdentities) {
+		return nil, nil, errors.New("比赛中的问题不存在")
+	}
+	return &models.ContestBasic{
+		Title:   title,
+		Content: ctx.PostForm("content"),
+		StartAt: startAt,
+		EndAt:   endAt,
+	}, problemIdentities, nil
+}
+
+// contestProblems 按照顺序为比赛中的问题编号
+func contestProblems(contestIdentity string, problemIdentities []string) []*models.ContestProblem {
+	res := make([]*models.ContestProblem, 0, len(problemIdentities))
+	for i, identity := range problemIdentities {
+		res = append(res, &models.ContestProblem{
+			ContestIdentity: contestIdentity,
+			ProblemIdentity: identity,
+			Letter:          string(rune('A' + i)),
+		})
+	}
+	return res
+}
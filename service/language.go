@@ -0,0 +1,144 @@
+package service
+
+import (
+	"gin_gorm_oj/judge"
+	"gin_gorm_oj/models"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetLanguageLimitList
+// @Tags 管理员私有方法
+// @Summary 编程语言限制规则列表
+// @Param authorization header string true "authorization"
+// @Param problem_identity query string false "问题唯一标识，为空时获取全局规则"
+// @Success 200 {string} json "{"code":"200","data":""}"
+// @Router /admin/language-limit-list [get]
+func GetLanguageLimitList(ctx *gin.Context) {
+	problemIdentity := ctx.Query("problem_identity")
+	list := make([]*models.LanguageLimit, 0)
+	err := models.DB.Where("problem_identity = ?", problemIdentity).Find(&list).Error
+	if err != nil {
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  "Get languageLimitList Error:" + err.Error(),
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"data": map[string]interface{}{
+			"list":      list,
+			"languages": judge.Languages,
+		},
+	})
+}
+
+// LanguageLimitModify
+// @Tags 管理员私有方法
+// @Summary 编程语言限制规则修改
+// @Description 不存在时创建；max_runtime、max_mem不为0时覆盖倍数；problem_identity为空时修改全局规则
+// @Param authorization header string true "authorization"
+// @Param problem_identity formData string false "problem_identity"
+// @Param language formData string true "language"
+// @Param time_factor formData number false "time_factor"
+// @Param mem_factor formData number false "mem_factor"
+// @Param max_runtime formData int false "max_runtime"
+// @Param max_mem formData int false "max_mem"
+// @Success 200 {string} json "{"code":"200","msg":"","data":""}"
+// @Router /admin/language-limit-modify [put]
+func LanguageLimitModify(ctx *gin.Context) {
+	problemIdentity := ctx.PostForm("problem_identity")
+	language := ctx.PostForm("language")
+	timeFactor, _ := strconv.ParseFloat(ctx.PostForm("time_factor"), 64)
+	memFactor, _ := strconv.ParseFloat(ctx.PostForm("mem_factor"), 64)
+	maxRuntime, _ := strconv.Atoi(ctx.PostForm("max_runtime"))
+	maxMem, _ := strconv.Atoi(ctx.PostForm("max_mem"))
+	if _, ok := judge.Languages[language]; !ok {
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  "不支持的编程语言",
+		})
+		return
+	}
+	if timeFactor < 0 || memFactor < 0 || maxRuntime < 0 || maxMem < 0 {
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  "参数不正确",
+		})
+		return
+	}
+	data := new(models.LanguageLimit)
+	err := models.DB.Where("problem_identity = ? AND language = ?", problemIdentity, language).First(data).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  "Get languageLimit Error:" + err.Error(),
+		})
+		return
+	}
+	if err == gorm.ErrRecordNotFound {
+		err = models.DB.Create(&models.LanguageLimit{
+			ProblemIdentity: problemIdentity,
+			Language:        language,
+			TimeFactor:      timeFactor,
+			MemFactor:       memFactor,
+			MaxRuntime:      maxRuntime,
+			MaxMem:          maxMem,
+		}).Error
+	} else {
+		// 使用map更新，允许将字段改为0
+		err = models.DB.Model(data).Updates(map[string]interface{}{
+			"time_factor": timeFactor,
+			"mem_factor":  memFactor,
+			"max_runtime": maxRuntime,
+			"max_mem":     maxMem,
+		}).Error
+	}
+	if err != nil {
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  "编程语言限制规则修改失败",
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "编程语言限制规则修改成功",
+	})
+}
+
+// LanguageLimitDelete
+// @Tags 管理员私有方法
+// @Summary 编程语言限制规则删除
+// @Param authorization header string true "authorization"
+// @Param problem_identity query string false "problem_identity"
+// @Param language query string true "language"
+// @Success 200 {string} json "{"code":"200","msg":"","data":""}"
+// @Router /admin/language-limit-delete [delete]
+func LanguageLimitDelete(ctx *gin.Context) {
+	problemIdentity := ctx.Query("problem_identity")
+	language := ctx.Query("language")
+	if language == "" {
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  "参数不正确,language",
+		})
+		return
+	}
+	err := models.DB.Where("problem_identity = ? AND language = ?", problemIdentity, language).Delete(new(models.LanguageLimit)).Error
+	if err != nil {
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  "删除编程语言限制规则失败",
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "编程语言限制规则删除成功",
+	})
+}
@@ -4,10 +4,15 @@ import (
 	"encoding/json"
 	"errors"
 	"gin_gorm_oj/define"
+	"gin_gorm_oj/dispatch"
 	"gin_gorm_oj/helper"
+	"gin_gorm_oj/judge"
 	"gin_gorm_oj/models"
+	"io"
 	"log"
 	"net/http"
+	"os"
+	"path/filepath"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
@@ -82,6 +87,41 @@ func GetProblemDetail(ctx *gin.Context) {
 		})
 		return
 	}
+	// 未开始的比赛中的问题不公开
+	hidden, err := models.IsProblemHidden(identity)
+	if err != nil {
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  "Get problemDetail Error:" + err.Error(),
+		})
+		return
+	}
+	if hidden {
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  "当前问题不存在",
+		})
+		return
+	}
+	// 各编程语言下实际的运行限制
+	rules, err := models.GetLanguageLimits(identity)
+	if err != nil {
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  "Get languageLimit Error:" + err.Error(),
+		})
+		return
+	}
+	data.Limits = models.EffectiveLimits(data, rules)
+	// 只返回样例的数据
+	data.Samples, err = models.GetSamples(ctx.Request.Context(), identity)
+	if err != nil {
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  "Get samples Error:" + err.Error(),
+		})
+		return
+	}
 	ctx.JSON(http.StatusOK, gin.H{
 		"code": 200,
 		"data": data,
@@ -96,8 +136,12 @@ func GetProblemDetail(ctx *gin.Context) {
 // @Param content formData string true "content"
 // @Param max_mem formData int true "max_mem"
 // @Param max_runtime formData int true "max_runtime"
+// @Param max_output formData int false "输出限制(KB)，为0时使用默认的输出限制"
 // @Param category_ids formData []string false "category_ids" collectionFormat(multi)
-// @Param test_cases formData []string true "test_cases" collectionFormat(multi)
+// @Param test_cases formData []string true "测试用例，如{\"input\":\"1 2\",\"output\":\"3\",\"sample\":true}，sample为true时为公开的样例" collectionFormat(multi)
+// @Param compare_mode formData string false "输出比较方式：exact、trailing、token、nocase、float，默认exact"
+// @Param abs_epsilon formData number false "浮点数比较的绝对误差"
+// @Param rel_epsilon formData number false "浮点数比较的相对误差"
 // @Success 200 {string} json "{"code":"200","msg":"","data":""}"
 // @Router /admin/problem-create [post]
 func ProblemCreate(ctx *gin.Context) {
@@ -105,6 +149,7 @@ func ProblemCreate(ctx *gin.Context) {
 	content := ctx.PostForm("content")
 	maxMem, _ := strconv.Atoi(ctx.PostForm("max_mem"))
 	maxRuntime, _ := strconv.Atoi(ctx.PostForm("max_runtime"))
+	maxOutput, _ := strconv.Atoi(ctx.PostForm("max_output"))
 	categoryIds := ctx.PostFormArray("category_ids")
 	testCases := ctx.PostFormArray("test_cases")
 
@@ -115,13 +160,25 @@ func ProblemCreate(ctx *gin.Context) {
 		})
 		return
 	}
+	cmp, err := problemCompare(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  err.Error(),
+		})
+		return
+	}
 	identity := helper.GetUUID()
 	data := models.ProblemBasic{
-		Title:      title,
-		Content:    content,
-		MaxMem:     maxMem,
-		MaxRuntime: maxRuntime,
-		Identity:   identity,
+		Title:       title,
+		Content:     content,
+		MaxMem:      maxMem,
+		MaxRuntime:  maxRuntime,
+		MaxOutput:   maxOutput,
+		Identity:    identity,
+		CompareMode: cmp.Mode,
+		AbsEpsilon:  cmp.AbsEpsilon,
+		RelEpsilon:  cmp.RelEpsilon,
 	}
 	// 处理分类
 	categoryBasic := make([]*models.ProblemCategory, 0)
@@ -137,8 +194,8 @@ func ProblemCreate(ctx *gin.Context) {
 	// 处理测试用例
 	testCaseBasics := make([]*models.TestCase, 0)
 	for _, testCase := range testCases {
-		caseMap := make(map[string]string)
-		err := json.Unmarshal([]byte(testCase), &caseMap)
+		caseMap := new(testCaseForm)
+		err := json.Unmarshal([]byte(testCase), caseMap)
 		if err != nil {
 			ctx.JSON(http.StatusOK, gin.H{
 				"code": -1,
@@ -146,34 +203,40 @@ func ProblemCreate(ctx *gin.Context) {
 			})
 			return
 		}
-		if _, ok := caseMap["input"]; !ok {
+		if caseMap.Input == nil {
 			ctx.JSON(http.StatusOK, gin.H{
 				"code": -1,
 				"msg":  "测试用例格式错误 input",
 			})
 			return
 		}
-		if _, ok := caseMap["output"]; !ok {
+		if caseMap.Output == nil {
 			ctx.JSON(http.StatusOK, gin.H{
 				"code": -1,
 				"msg":  "测试用例格式错误 output",
 			})
 			return
 		}
-		testCaseBasic := &models.TestCase{
-			Identity:        helper.GetUUID(),
-			ProblemIdentity: identity,
-			Input:           caseMap["input"],
-			Output:          caseMap["output"],
+		// 测试数据保存到存储中
+		testCaseBasic, err := models.NewTestCase(ctx.Request.Context(), identity, helper.GetUUID(), *caseMap.Input, *caseMap.Output)
+		if err != nil {
+			models.DeleteTestCaseData(ctx.Request.Context(), testCaseBasics)
+			ctx.JSON(http.StatusOK, gin.H{
+				"code": -1,
+				"msg":  "测试用例保存失败:" + err.Error(),
+			})
+			return
 		}
+		testCaseBasic.IsSample = caseMap.Sample
 		testCaseBasics = append(testCaseBasics, testCaseBasic)
 
 	}
 	data.TestCase = testCaseBasics
 
 	// 创建问题
-	err := models.DB.Create(&data).Error
+	err = models.DB.Create(&data).Error
 	if err != nil {
+		models.DeleteTestCaseData(ctx.Request.Context(), testCaseBasics)
 		ctx.JSON(http.StatusOK, gin.H{
 			"code": -1,
 			"msg":  "problem create err:" + err.Error(),
@@ -198,8 +261,12 @@ func ProblemCreate(ctx *gin.Context) {
 // @Param content formData string true "content"
 // @Param max_mem formData int true "max_mem"
 // @Param max_runtime formData int true "max_runtime"
+// @Param max_output formData int false "输出限制(KB)，为0时使用默认的输出限制，不传时不修改"
 // @Param category_ids formData []string false "category_ids" collectionFormat(multi)
-// @Param test_cases formData []string true "test_cases" collectionFormat(multi)
+// @Param test_cases formData []string true "测试用例，如{\"input\":\"1 2\",\"output\":\"3\",\"sample\":true}，sample为true时为公开的样例" collectionFormat(multi)
+// @Param compare_mode formData string false "输出比较方式：exact、trailing、token、nocase、float，不传时不修改"
+// @Param abs_epsilon formData number false "浮点数比较的绝对误差，不传时不修改"
+// @Param rel_epsilon formData number false "浮点数比较的相对误差，不传时不修改"
 // @Success 200 {string} json "{"code":"200","msg":"","data":""}"
 // @Router /admin/problem-modify [put]
 func ProblemMotify(ctx *gin.Context) {
@@ -208,6 +275,7 @@ func ProblemMotify(ctx *gin.Context) {
 	content := ctx.PostForm("content")
 	maxMem, _ := strconv.Atoi(ctx.PostForm("max_mem"))
 	maxRuntime, _ := strconv.Atoi(ctx.PostForm("max_runtime"))
+	maxOutput, _ := strconv.Atoi(ctx.PostForm("max_output"))
 	categoryIds := ctx.PostFormArray("category_ids")
 	testCases := ctx.PostFormArray("test_cases")
 
@@ -218,6 +286,36 @@ func ProblemMotify(ctx *gin.Context) {
 		})
 		return
 	}
+	cmp, err := problemCompare(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  err.Error(),
+		})
+		return
+	}
+	optional := make(map[string]interface{})
+	for field, value := range map[string]interface{}{
+		"compare_mode": cmp.Mode,
+		"abs_epsilon":  cmp.AbsEpsilon,
+		"rel_epsilon":  cmp.RelEpsilon,
+		"max_output":   maxOutput,
+	} {
+		if _, ok := ctx.GetPostForm(field); ok {
+			optional[field] = value
+		}
+	}
+	// 原有的测试数据在修改成功后删除，新的测试数据在修改失败时删除
+	oldTcs := make([]*models.TestCase, 0)
+	err = models.DB.Where("problem_identity = ?", identity).Find(&oldTcs).Error
+	if err != nil {
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  "Get TestCase Error:" + err.Error(),
+		})
+		return
+	}
+	tcs := make([]*models.TestCase, 0)
 	if err := models.DB.Transaction(func(tx *gorm.DB) error {
 		// 问题基础信息保存
 		problemBasic := &models.ProblemBasic{
@@ -231,6 +329,13 @@ func ProblemMotify(ctx *gin.Context) {
 		if err != nil {
 			return err
 		}
+		// 比较方式、误差和输出限制只在传入时修改，可以改为0，使用map更新
+		if len(optional) > 0 {
+			err = tx.Model(new(models.ProblemBasic)).Where("identity = ?", identity).Updates(optional).Error
+			if err != nil {
+				return err
+			}
+		}
 		// 查询问题详情
 		err = tx.Where("identity = ?", identity).Find(problemBasic).Error
 		if err != nil {
@@ -265,25 +370,24 @@ func ProblemMotify(ctx *gin.Context) {
 			return err
 		}
 		// 2. 增加新的关联关系
-		tcs := make([]*models.TestCase, 0)
 		for _, testCase := range testCases {
-			caseMap := make(map[string]string)
-			err = json.Unmarshal([]byte(testCase), &caseMap)
+			caseMap := new(testCaseForm)
+			err = json.Unmarshal([]byte(testCase), caseMap)
 			if err != nil {
 				return err
 			}
-			if _, ok := caseMap["input"]; !ok {
+			if caseMap.Input == nil {
 				return errors.New("测试案例格式错误")
 			}
-			if _, ok := caseMap["output"]; !ok {
+			if caseMap.Output == nil {
 				return errors.New("测试案例格式错误")
 			}
-			tcs = append(tcs, &models.TestCase{
-				Identity:        helper.GetUUID(),
-				ProblemIdentity: identity,
-				Input:           caseMap["input"],
-				Output:          caseMap["output"],
-			})
+			tc, err := models.NewTestCase(ctx.Request.Context(), identity, helper.GetUUID(), *caseMap.Input, *caseMap.Output)
+			if err != nil {
+				return err
+			}
+			tc.IsSample = caseMap.Sample
+			tcs = append(tcs, tc)
 
 		}
 		err = tx.Create(&tcs).Model(new(models.TestCase)).Error
@@ -292,12 +396,14 @@ func ProblemMotify(ctx *gin.Context) {
 		}
 		return nil
 	}); err != nil {
+		models.DeleteTestCaseData(ctx.Request.Context(), tcs)
 		ctx.JSON(http.StatusOK, gin.H{
 			"code": -1,
 			"msg":  "问题修改失败,err :" + err.Error(),
 		})
 		return
 	}
+	models.DeleteTestCaseData(ctx.Request.Context(), oldTcs)
 
 	ctx.JSON(http.StatusOK, gin.H{
 		"code": 200,
@@ -305,3 +411,193 @@ func ProblemMotify(ctx *gin.Context) {
 	})
 
 }
+
+// testCaseForm test_cases中的一个测试用例，sample为true时为样例
+type testCaseForm struct {
+	Input  *string `json:"input"`
+	Output *string `json:"output"`
+	Sample bool    `json:"sample"`
+}
+
+// problemCompare 读取问题的输出比较方式，默认逐字节比较
+func problemCompare(ctx *gin.Context) (judge.Compare, error) {
+	cmp := judge.Compare{Mode: ctx.DefaultPostForm("compare_mode", judge.CompareExact)}
+	if !judge.CompareModes[cmp.Mode] {
+		return cmp, errors.New("不支持的比较方式:" + cmp.Mode)
+	}
+	var err error
+	if v := ctx.PostForm("abs_epsilon"); v != "" {
+		if cmp.AbsEpsilon, err = strconv.ParseFloat(v, 64); err != nil || cmp.AbsEpsilon < 0 {
+			return cmp, errors.New("参数不正确,abs_epsilon")
+		}
+	}
+	if v := ctx.PostForm("rel_epsilon"); v != "" {
+		if cmp.RelEpsilon, err = strconv.ParseFloat(v, 64); err != nil || cmp.RelEpsilon < 0 {
+			return cmp, errors.New("参数不正确,rel_epsilon")
+		}
+	}
+	return cmp, nil
+}
+
+// ProblemChecker
+// @Tags 管理员私有方法
+// @Summary 上传评测程序
+// @Description 评测程序的参数依次为输入文件、标准输出文件和用户输出文件，退出码0为正确，1、2为错误，输出作为判断信息
+// @Param authorization header string true "authorization"
+// @Param identity formData string true "问题唯一标识"
+// @Param language formData string false "评测程序的编程语言，默认go"
+// @Param file formData file true "评测程序代码"
+// @Success 200 {string} json "{"code":"200","msg":"","data":""}"
+// @Router /admin/problem-checker [post]
+func ProblemChecker(ctx *gin.Context) {
+	problemProgramSave(ctx, "checker", "评测程序")
+}
+
+// ProblemCheckerDelete
+// @Tags 管理员私有方法
+// @Summary 删除评测程序，恢复按照比较方式判断
+// @Param authorization header string true "authorization"
+// @Param identity query string true "问题唯一标识"
+// @Success 200 {string} json "{"code":"200","msg":"","data":""}"
+// @Router /admin/problem-checker-delete [delete]
+func ProblemCheckerDelete(ctx *gin.Context) {
+	problemProgramDelete(ctx, "checker", "评测程序")
+}
+
+// ProblemInteractor
+// @Tags 管理员私有方法
+// @Summary 上传交互程序，问题变为交互题
+// @Description 交互程序的参数依次为输入文件和标准输出文件，标准输入输出与用户程序相连，退出码0为正确，1、2为错误，标准错误作为判断信息
+// @Param authorization header string true "authorization"
+// @Param identity formData string true "问题唯一标识"
+// @Param language formData string false "交互程序的编程语言，默认go"
+// @Param file formData file true "交互程序代码"
+// @Success 200 {string} json "{"code":"200","msg":"","data":""}"
+// @Router /admin/problem-interactor [post]
+func ProblemInteractor(ctx *gin.Context) {
+	problemProgramSave(ctx, "interactor", "交互程序")
+}
+
+// ProblemInteractorDelete
+// @Tags 管理员私有方法
+// @Summary 删除交互程序，问题恢复为普通题
+// @Param authorization header string true "authorization"
+// @Param identity query string true "问题唯一标识"
+// @Success 200 {string} json "{"code":"200","msg":"","data":""}"
+// @Router /admin/problem-interactor-delete [delete]
+func ProblemInteractorDelete(ctx *gin.Context) {
+	problemProgramDelete(ctx, "interactor", "交互程序")
+}
+
+// problemProgramSave 编译检查并保存问题的评测程序或交互程序，kind为字段前缀
+func problemProgramSave(ctx *gin.Context, kind, name string) {
+	identity := ctx.PostForm("identity")
+	lang, ok := judge.GetLanguage(ctx.PostForm("language"))
+	if identity == "" || !ok {
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  "参数不正确",
+		})
+		return
+	}
+	old := new(models.ProblemBasic)
+	err := models.DB.Where("identity = ?", identity).First(old).Error
+	if err != nil {
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  "当前问题不存在",
+		})
+		return
+	}
+	file, err := ctx.FormFile("file")
+	if err != nil {
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  "读取" + name + "失败:" + err.Error(),
+		})
+		return
+	}
+	f, err := file.Open()
+	if err != nil {
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  "读取" + name + "失败:" + err.Error(),
+		})
+		return
+	}
+	defer f.Close()
+	code, err := io.ReadAll(f)
+	if err != nil {
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  "读取" + name + "失败:" + err.Error(),
+		})
+		return
+	}
+	// 上传时由判题节点编译检查，判题时使用判题节点缓存的编译结果
+	if err := dispatch.Compile(ctx.Request.Context(), lang.Name, string(code)); err != nil {
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  name + "编译失败:" + err.Error(),
+		})
+		return
+	}
+	path, err := helper.CodeSave(code, lang.SourceFile)
+	if err != nil {
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  "Code Save Error:" + err.Error(),
+		})
+		return
+	}
+	err = models.DB.Model(new(models.ProblemBasic)).Where("identity = ?", identity).Updates(map[string]interface{}{
+		kind + "_path":     path,
+		kind + "_language": lang.Name,
+	}).Error
+	if err != nil {
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  name + "保存失败:" + err.Error(),
+		})
+		return
+	}
+	if oldPath := old.ProgramPath(kind); oldPath != "" {
+		os.RemoveAll(filepath.Dir(oldPath))
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  name + "上传成功",
+	})
+}
+
+// problemProgramDelete 删除问题的评测程序或交互程序
+func problemProgramDelete(ctx *gin.Context, kind, name string) {
+	identity := ctx.Query("identity")
+	data := new(models.ProblemBasic)
+	err := models.DB.Where("identity = ?", identity).First(data).Error
+	if err != nil {
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  "当前问题不存在",
+		})
+		return
+	}
+	err = models.DB.Model(new(models.ProblemBasic)).Where("identity = ?", identity).Updates(map[string]interface{}{
+		kind + "_path":     "",
+		kind + "_language": "",
+	}).Error
+	if err != nil {
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  "删除" + name + "失败",
+		})
+		return
+	}
+	if path := data.ProgramPath(kind); path != "" {
+		os.RemoveAll(filepath.Dir(path))
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  name + "删除成功",
+	})
+}
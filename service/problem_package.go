@@ -0,0 +1,350 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"gin_gorm_oj/archive"
+	"gin_gorm_oj/dispatch"
+	"gin_gorm_oj/helper"
+	"gin_gorm_oj/judge"
+	"gin_gorm_oj/models"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ProblemExport
+// @Tags 管理员私有方法
+// @Summary 导出题目包
+// @Description 题目包为zip压缩包，包含problem.yaml（标题、限制、比较方式、分类等）、statement.md（题面）、tests目录下的测试用例以及评测程序、交互程序的代码
+// @Param authorization header string true "authorization"
+// @Param identity query string true "问题唯一标识"
+// @Success 200 {string} json "{"code":"200","msg":"","data":""}"
+// @Router /admin/problem-export [get]
+func ProblemExport(ctx *gin.Context) {
+	identity := ctx.Query("identity")
+	data := new(models.ProblemBasic)
+	err := models.DB.Where("identity = ?", identity).
+		Preload("ProblemCategories").Preload("ProblemCategories.CategoryBasic").
+		Preload("TestCase", func(db *gorm.DB) *gorm.DB {
+			return db.Order("id")
+		}).First(data).Error
+	if err != nil {
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  "当前问题不存在",
+		})
+		return
+	}
+	rules := make([]*models.LanguageLimit, 0)
+	err = models.DB.Where("problem_identity = ?", identity).Find(&rules).Error
+	if err != nil {
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  "Get languageLimit Error:" + err.Error(),
+		})
+		return
+	}
+
+	p := &archive.Problem{
+		Title:       data.Title,
+		MaxRuntime:  data.MaxRuntime,
+		MaxMem:      data.MaxMem,
+		MaxOutput:   data.MaxOutput,
+		CompareMode: data.CompareMode,
+		AbsEpsilon:  data.AbsEpsilon,
+		RelEpsilon:  data.RelEpsilon,
+	}
+	for _, pc := range data.ProblemCategories {
+		if pc.CategoryBasic != nil {
+			p.Categories = append(p.Categories, pc.CategoryBasic.Name)
+		}
+	}
+	for _, r := range rules {
+		p.LanguageLimits = append(p.LanguageLimits, archive.LanguageLimit{
+			Language:   r.Language,
+			TimeFactor: r.TimeFactor,
+			MemFactor:  r.MemFactor,
+			MaxRuntime: r.MaxRuntime,
+			MaxMem:     r.MaxMem,
+		})
+	}
+	for i, tc := range data.TestCase {
+		if tc.IsSample {
+			p.Samples = append(p.Samples, strconv.Itoa(i+1))
+		}
+	}
+	if data.CheckerPath != "" {
+		p.Checker = &archive.Program{Language: data.CheckerLanguage, Source: "checker/" + filepath.Base(data.CheckerPath)}
+	}
+	if data.InteractorPath != "" {
+		p.Interactor = &archive.Program{Language: data.InteractorLanguage, Source: "interactor/" + filepath.Base(data.InteractorPath)}
+	}
+	meta, err := p.Marshal()
+	if err != nil {
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  "problem export err:" + err.Error(),
+		})
+		return
+	}
+
+	// 边读取测试数据边写入压缩包，开始写入后出错只能中断响应
+	ctx.Header("Content-Type", "application/zip")
+	ctx.Header("Content-Disposition", `attachment; filename="`+identity+`.zip"`)
+	ctx.Status(http.StatusOK)
+	zw := zip.NewWriter(ctx.Writer)
+	err = writeProblemPackage(ctx.Request.Context(), zw, data, meta, p)
+	if err == nil {
+		err = zw.Close()
+	}
+	if err != nil {
+		log.Println("problem export err:", identity, err)
+		ctx.Abort()
+	}
+}
+
+// writeProblemPackage 写入题目包中的各个文件
+func writeProblemPackage(ctx context.Context, zw *zip.Writer, data *models.ProblemBasic, meta []byte, p *archive.Problem) error {
+	if err := writeZipFile(zw, archive.ProblemFile, meta); err != nil {
+		return err
+	}
+	if err := writeZipFile(zw, archive.StatementFile, []byte(data.Content)); err != nil {
+		return err
+	}
+	for i, tc := range data.TestCase {
+		name := archive.TestsDir + strconv.Itoa(i+1)
+		in, err := tc.OpenInput(ctx)
+		if err != nil {
+			return err
+		}
+		err = copyZipFile(zw, name+".in", in)
+		in.Close()
+		if err != nil {
+			return err
+		}
+		out, err := tc.OpenOutput(ctx)
+		if err != nil {
+			return err
+		}
+		err = copyZipFile(zw, name+".out", out)
+		out.Close()
+		if err != nil {
+			return err
+		}
+	}
+	programs := []struct {
+		prog *archive.Program
+		path string
+	}{
+		{p.Checker, data.CheckerPath},
+		{p.Interactor, data.InteractorPath},
+	}
+	for _, pr := range programs {
+		if pr.prog == nil {
+			continue
+		}
+		code, err := os.ReadFile(pr.path)
+		if err != nil {
+			return err
+		}
+		if err := writeZipFile(zw, pr.prog.Source, code); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeZipFile(zw *zip.Writer, name string, data []byte) error {
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+func copyZipFile(zw *zip.Writer, name string, r io.Reader) error {
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, r)
+	return err
+}
+
+// ProblemImport
+// @Tags 管理员私有方法
+// @Summary 导入题目包
+// @Description 每个文件为一个题目包，支持/admin/problem-export导出的problem.yaml格式和Polygon的完整题目包(problem.xml)，Polygon的标签导入为分类
+// @Description 逐个导入，单个题目包导入失败不影响其他题目包，data中依次给出每个题目包的导入结果
+// @Param authorization header string true "authorization"
+// @Param files formData file true "题目包zip压缩包，可以上传多个"
+// @Success 200 {string} json "{"code":"200","msg":"","data":""}"
+// @Router /admin/problem-import [post]
+func ProblemImport(ctx *gin.Context) {
+	form, err := ctx.MultipartForm()
+	if err != nil || len(form.File["files"]) == 0 {
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  "请上传题目包",
+		})
+		return
+	}
+	results := make([]gin.H, 0, len(form.File["files"]))
+	success := 0
+	for _, file := range form.File["files"] {
+		res := gin.H{"file": file.Filename}
+		pb, err := importProblem(ctx.Request.Context(), file)
+		if err != nil {
+			log.Println("problem import err:", file.Filename, err)
+			res["msg"] = "导入失败:" + err.Error()
+		} else {
+			success++
+			res["identity"] = pb.Identity
+			res["title"] = pb.Title
+			res["msg"] = "导入成功"
+		}
+		results = append(results, res)
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "成功导入" + strconv.Itoa(success) + "个题目，失败" + strconv.Itoa(len(results)-success) + "个",
+		"data": results,
+	})
+}
+
+// importProblem 导入一个题目包，出错时清理已经保存的测试数据和代码
+func importProblem(ctx context.Context, file *multipart.FileHeader) (*models.ProblemBasic, error) {
+	f, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	zr, err := zip.NewReader(f, file.Size)
+	if err != nil {
+		return nil, err
+	}
+	pkg, err := archive.Open(zr)
+	if err != nil {
+		return nil, err
+	}
+	if pkg.Title == "" || pkg.MaxRuntime <= 0 || pkg.MaxMem <= 0 {
+		return nil, errors.New("标题、时间限制和内存限制不能为空")
+	}
+	if pkg.CompareMode != "" && !judge.CompareModes[pkg.CompareMode] {
+		return nil, errors.New("不支持的比较方式:" + pkg.CompareMode)
+	}
+	for _, r := range pkg.LanguageLimits {
+		if _, ok := judge.GetLanguage(r.Language); !ok {
+			return nil, errors.New("不支持的编程语言:" + r.Language)
+		}
+	}
+
+	pb := &models.ProblemBasic{
+		Identity:    helper.GetUUID(),
+		Title:       pkg.Title,
+		Content:     pkg.Content,
+		MaxMem:      pkg.MaxMem,
+		MaxRuntime:  pkg.MaxRuntime,
+		MaxOutput:   pkg.MaxOutput,
+		CompareMode: pkg.CompareMode,
+		AbsEpsilon:  pkg.AbsEpsilon,
+		RelEpsilon:  pkg.RelEpsilon,
+	}
+	// 评测程序和交互程序在导入时编译
+	var paths []string
+	defer func() {
+		if err != nil {
+			for _, path := range paths {
+				os.RemoveAll(filepath.Dir(path))
+			}
+		}
+	}()
+	programs := []struct {
+		prog           *archive.Program
+		path, language *string
+	}{
+		{pkg.Checker, &pb.CheckerPath, &pb.CheckerLanguage},
+		{pkg.Interactor, &pb.InteractorPath, &pb.InteractorLanguage},
+	}
+	for _, p := range programs {
+		if p.prog == nil {
+			continue
+		}
+		*p.path, err = importProgram(ctx, pkg, p.prog)
+		if err != nil {
+			return nil, err
+		}
+		*p.language = p.prog.Language
+		paths = append(paths, *p.path)
+	}
+
+	tcs, err := saveTestCaseFiles(ctx, pb.Identity, pkg.Tests)
+	if err != nil {
+		return nil, err
+	}
+	pb.TestCase = tcs
+	err = models.DB.Transaction(func(tx *gorm.DB) error {
+		// 按照名称关联分类，不存在时创建
+		added := make(map[string]bool)
+		for _, name := range pkg.Categories {
+			if name == "" || added[name] {
+				continue
+			}
+			added[name] = true
+			cb := new(models.CategoryBasic)
+			err := tx.Where(models.CategoryBasic{Name: name}).Attrs(models.CategoryBasic{Identity: helper.GetUUID()}).FirstOrCreate(cb).Error
+			if err != nil {
+				return err
+			}
+			pb.ProblemCategories = append(pb.ProblemCategories, &models.ProblemCategory{CategoryId: cb.ID})
+		}
+		if err := tx.Create(pb).Error; err != nil {
+			return err
+		}
+		for _, r := range pkg.LanguageLimits {
+			err := tx.Create(&models.LanguageLimit{
+				ProblemIdentity: pb.Identity,
+				Language:        r.Language,
+				TimeFactor:      r.TimeFactor,
+				MemFactor:       r.MemFactor,
+				MaxRuntime:      r.MaxRuntime,
+				MaxMem:          r.MaxMem,
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		models.DeleteTestCaseData(ctx, tcs)
+		return nil, err
+	}
+	return pb, nil
+}
+
+// importProgram 编译检查并保存题目包中的评测程序或交互程序，返回代码路径
+func importProgram(ctx context.Context, pkg *archive.Package, prog *archive.Program) (string, error) {
+	lang, ok := judge.GetLanguage(prog.Language)
+	if !ok {
+		return "", errors.New("不支持的编程语言:" + prog.Language)
+	}
+	code, err := pkg.Source(prog)
+	if err != nil {
+		return "", err
+	}
+	if err := dispatch.Compile(ctx, lang.Name, string(code)); err != nil {
+		return "", errors.New(prog.Source + "编译失败:" + err.Error())
+	}
+	return helper.CodeSave(code, lang.SourceFile)
+}
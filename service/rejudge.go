@@ -0,0 +1,91 @@
+package service
+
+import (
+	"gin_gorm_oj/dispatch"
+	"gin_gorm_oj/models"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Rejudge
+// @Tags 管理员私有方法
+// @Summary 重新判断提交
+// @Description 修改测试用例后按照保存的代码重新判断提交，并重新统计相关用户和问题的通过个数
+// @Description 指定submit_identity时只重新判断该提交，否则按照问题、用户、状态和编程语言筛选，至少指定一个条件；待判断的提交不会重新判断
+// @Description force为true时待判断超过RejudgePendingTimeout的提交视为判题节点已经丢失，同样重新判断
+// @Param authorization header string true "authorization"
+// @Param submit_identity formData string false "提交唯一标识"
+// @Param problem_identity formData string false "问题唯一标识"
+// @Param user_identity formData string false "用户唯一标识"
+// @Param status formData int false "判断状态"
+// @Param language formData string false "编程语言"
+// @Param force formData bool false "是否强制重新判断长时间待判断的提交"
+// @Success 200 {string} json "{"code":"200","msg":"","data":""}"
+// @Router /admin/rejudge [post]
+func Rejudge(ctx *gin.Context) {
+	submitIdentity := ctx.PostForm("submit_identity")
+	problemIdentity := ctx.PostForm("problem_identity")
+	userIdentity := ctx.PostForm("user_identity")
+	status, _ := strconv.Atoi(ctx.PostForm("status"))
+	language := ctx.PostForm("language")
+	force, _ := strconv.ParseBool(ctx.PostForm("force"))
+	if submitIdentity == "" && problemIdentity == "" && userIdentity == "" && status == 0 && language == "" {
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  "至少指定一个筛选条件",
+		})
+		return
+	}
+	tx := models.DB.Model(new(models.SubmitBasic)).Select("identity", "problem_identity", "user_identity", "status", "updated_at")
+	if submitIdentity != "" {
+		tx = tx.Where("identity = ?", submitIdentity)
+	}
+	if problemIdentity != "" {
+		tx = tx.Where("problem_identity = ?", problemIdentity)
+	}
+	if userIdentity != "" {
+		tx = tx.Where("user_identity = ?", userIdentity)
+	}
+	if status != 0 {
+		tx = tx.Where("status = ?", status)
+	}
+	if language != "" {
+		tx = tx.Where("language = ?", language)
+	}
+	list := make([]*models.SubmitBasic, 0)
+	err := tx.Order("id").Find(&list).Error
+	if err != nil {
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  "Get submitList Error:" + err.Error(),
+		})
+		return
+	}
+	if submitIdentity != "" && len(list) == 0 {
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  "当前提交不存在",
+		})
+		return
+	}
+	cnt, err := dispatch.Rejudge(ctx, list, force)
+	if err != nil {
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  "Rejudge Error:" + err.Error(),
+			"data": gin.H{
+				"count": cnt,
+			},
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "已重新判断",
+		"data": gin.H{
+			"count": cnt,
+		},
+	})
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"gin_gorm_oj/define"
+	"gin_gorm_oj/dispatch"
+	"gin_gorm_oj/helper"
+	"gin_gorm_oj/judge"
+	"gin_gorm_oj/models"
+	"gin_gorm_oj/queue"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Run
+// @Tags 用户私有方法
+// @Summary 使用自定义输入运行代码
+// @Description 编译代码并以input为标准输入运行，返回标准输出、标准错误、运行时间和内存，不创建提交，不影响提交数和排名
+// @Description 指定问题时使用问题在该编程语言下的时间和内存限制，未开始的比赛中的问题视为不存在
+// @Param authorization header string true "authorization"
+// @Param problem_identity formData string false "problem_identity"
+// @Param language formData string false "编程语言：go、c、cpp、python、java，默认go"
+// @Param code formData string true "code"
+// @Param input formData string false "标准输入"
+// @Success 200 {string} json "{"code":"200","msg":"","data":""}"
+// @Router /user/run [post]
+func Run(ctx *gin.Context) {
+	problemIdentity := ctx.PostForm("problem_identity")
+	code := ctx.PostForm("code")
+	input := ctx.PostForm("input")
+	lang, ok := judge.GetLanguage(ctx.PostForm("language"))
+	if !ok {
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  "不支持的编程语言",
+		})
+		return
+	}
+	if code == "" {
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  "代码不能为空",
+		})
+		return
+	}
+	if len(input) > define.RunMaxInput {
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  "输入过长",
+		})
+		return
+	}
+	limit := judge.Limit{
+		MaxRuntime: define.RunMaxRuntime,
+		MaxMem:     define.RunMaxMem,
+	}
+	if problemIdentity != "" {
+		pb := new(models.ProblemBasic)
+		err := models.DB.Where("identity = ?", problemIdentity).First(pb).Error
+		if err != nil {
+			ctx.JSON(http.StatusOK, gin.H{
+				"code": -1,
+				"msg":  "当前问题不存在",
+			})
+			return
+		}
+		// 未开始的比赛中的问题不公开，不能通过运行限制探测
+		hidden, err := models.IsProblemHidden(problemIdentity)
+		if err != nil {
+			ctx.JSON(http.StatusOK, gin.H{
+				"code": -1,
+				"msg":  "get problem err:" + err.Error(),
+			})
+			return
+		}
+		if hidden {
+			ctx.JSON(http.StatusOK, gin.H{
+				"code": -1,
+				"msg":  "当前问题不存在",
+			})
+			return
+		}
+		rules, err := models.GetLanguageLimits(problemIdentity)
+		if err != nil {
+			ctx.JSON(http.StatusOK, gin.H{
+				"code": -1,
+				"msg":  "Get languageLimit Error:" + err.Error(),
+			})
+			return
+		}
+		limit = models.EffectiveLimit(pb, lang, rules)
+	}
+	// 返回的输出不使用问题的输出限制
+	limit.MaxOutput = define.RunMaxOutput
+
+	job := &queue.Job{
+		Kind:     queue.KindRun,
+		Identity: helper.GetUUID(),
+		Language: lang.Name,
+		Code:     code,
+		Limit:    limit,
+		Input:    input,
+	}
+	// 由判题节点运行，优先于提交的判断
+	err := dispatch.Queue.Push(ctx, job)
+	if err != nil {
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  "push run err:" + err.Error(),
+		})
+		return
+	}
+	res, err := dispatch.Queue.WaitRunResult(ctx, job.Identity, time.Second*time.Duration(define.RunTimeout))
+	if err != nil {
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  "get run result err:" + err.Error(),
+		})
+		return
+	}
+	if res == nil {
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  "等待运行结果超时，请稍后重试",
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"data": map[string]interface{}{
+			"status": res.Status,
+			"msg":    res.Msg,
+			"stdout": res.Stdout,
+			"stderr": res.Stderr,
+			"time":   res.Time,
+			"mem":    res.Mem,
+		},
+	})
+}
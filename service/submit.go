@@ -1,19 +1,18 @@
 package service
 
 import (
-	"bytes"
 	"errors"
 	"gin_gorm_oj/define"
+	"gin_gorm_oj/dispatch"
 	"gin_gorm_oj/helper"
+	"gin_gorm_oj/judge"
 	"gin_gorm_oj/models"
+	"gin_gorm_oj/queue"
 	"io"
 	"io/ioutil"
 	"log"
 	"net/http"
-	"os/exec"
-	"runtime"
 	"strconv"
-	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -64,16 +63,161 @@ func GetSubmitList(ctx *gin.Context) {
 
 }
 
+// GetSubmitDetail
+// @Tags 公共方法
+// @Summary 提交详情，包含每个测试用例的判断结果
+// @Description 只返回样例的标准错误，其他测试用例的标准错误对所有用户（包括提交者）隐藏
+// @Param identity query string true "submit identity"
+// @Success 200 {string} json "{"code":"200","data":""}"
+// @Router /submit-detail [get]
+func GetSubmitDetail(ctx *gin.Context) {
+	identity := ctx.Query("identity")
+	if identity == "" {
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  "提交唯一标识不能为空",
+		})
+		return
+	}
+	data := new(models.SubmitBasic)
+	err := models.DB.Where("identity = ?", identity).Preload("ProblemBasic", func(db *gorm.DB) *gorm.DB {
+		return db.Omit("content")
+	}).Preload("CaseResults", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	}).First(data).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			ctx.JSON(http.StatusOK, gin.H{
+				"code": -1,
+				"msg":  "当前提交不存在",
+			})
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  "Get submitDetail Error:" + err.Error(),
+		})
+		return
+	}
+	// 非样例测试用例的标准错误不返回，提交者也不能查看
+	if err := models.HideStderr(data.ProblemIdentity, data.CaseResults); err != nil {
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  "Get submitDetail Error:" + err.Error(),
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"data": data,
+	})
+}
+
+// GetSubmitStatus
+// @Tags 公共方法
+// @Summary 提交的判断进度（Server-Sent Events）
+// @Description 以text/event-stream推送判断进度：judging开始判断，case每个测试用例结束（包含total、done和测试用例的结果，不包含标准错误），result判断结果已经保存（之后关闭连接）
+// @Description 提交已经判断完成时直接推送result，等待超过define.SubmitStatusTimeout时关闭连接，客户端可以重新连接
+// @Param identity query string true "submit identity"
+// @Success 200 {string} string "event: result"
+// @Router /submit-status [get]
+func GetSubmitStatus(ctx *gin.Context) {
+	identity := ctx.Query("identity")
+	if identity == "" {
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  "提交唯一标识不能为空",
+		})
+		return
+	}
+	// 先订阅再查询提交的状态，避免错过查询之后保存的判断结果
+	ps, err := dispatch.Queue.Subscribe(ctx, identity)
+	if err != nil {
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  "Subscribe Error:" + err.Error(),
+		})
+		return
+	}
+	defer ps.Close()
+	sb := new(models.SubmitBasic)
+	err = models.DB.Where("identity = ?", identity).First(sb).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			ctx.JSON(http.StatusOK, gin.H{
+				"code": -1,
+				"msg":  "当前提交不存在",
+			})
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  "Get submitStatus Error:" + err.Error(),
+		})
+		return
+	}
+	if sb.Status != judge.StatusPending {
+		ctx.SSEvent(queue.EventResult, &queue.Event{
+			Type:   queue.EventResult,
+			Result: &judge.Result{Status: sb.Status, Msg: sb.Msg, Time: sb.RunTime, Mem: sb.RunMem},
+		})
+		return
+	}
+
+	ch := ps.Channel()
+	timeout := time.NewTimer(time.Second * time.Duration(define.SubmitStatusTimeout))
+	defer timeout.Stop()
+	// 定时发送ping，避免连接被代理关闭
+	ping := time.NewTicker(time.Second * 15)
+	defer ping.Stop()
+	ctx.Stream(func(w io.Writer) bool {
+		select {
+		case msg, ok := <-ch:
+			if !ok {
+				return false
+			}
+			e, err := queue.ParseEvent(msg)
+			if err != nil {
+				log.Println("parse event err:", identity, err)
+				return true
+			}
+			// 测试用例可能是隐藏的，不推送标准错误
+			if e.Case != nil {
+				e.Case.Stderr = ""
+			}
+			ctx.SSEvent(e.Type, e)
+			return e.Type != queue.EventResult
+		case <-ping.C:
+			ctx.SSEvent("ping", "")
+			return true
+		case <-timeout.C:
+			return false
+		case <-ctx.Request.Context().Done():
+			return false
+		}
+	})
+}
+
 // Submit
 // @Tags 用户私有方法
 // @Summary 代码提交
+// @Description 提交后处于待判断状态(-1)，判断完成后通过提交详情查看结果
 // @Param authorization header string true "authorization"
 // @Param problem_identity query string true "problem_identity"
+// @Param language query string false "编程语言：go、c、cpp、python、java，默认go"
 // @Param code body string true "code"
 // @Success 200 {string} json "{"code":"200","msg":"","data":""}"
 // @Router /user/submit [post]
 func Submit(ctx *gin.Context) {
 	problemIdentity := ctx.Query("problem_identity")
+	lang, ok := judge.GetLanguage(ctx.Query("language"))
+	if !ok {
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  "不支持的编程语言",
+		})
+		return
+	}
 	code, err := ioutil.ReadAll(ctx.Request.Body)
 	if err != nil {
 		ctx.JSON(http.StatusOK, gin.H{
@@ -82,28 +226,53 @@ func Submit(ctx *gin.Context) {
 		})
 		return
 	}
-	// 代码保存
-	path, err := helper.CodeSave(code)
+	// 判断问题是否存在
+	var cnt int64
+	err = models.DB.Model(new(models.ProblemBasic)).Where("identity = ?", problemIdentity).Count(&cnt).Error
 	if err != nil {
 		ctx.JSON(http.StatusOK, gin.H{
 			"code": -1,
-			"msg":  "read code err:" + err.Error(),
+			"msg":  "get problem err:" + err.Error(),
+		})
+		return
+	}
+	if cnt == 0 {
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  "当前问题不存在",
+		})
+		return
+	}
+	// 未开始的比赛中的问题不能提交
+	hidden, err := models.IsProblemHidden(problemIdentity)
+	if err != nil {
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  "get problem err:" + err.Error(),
+		})
+		return
+	}
+	if hidden {
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  "当前问题不存在",
 		})
 		return
 	}
 	// 提交
 	u, _ := ctx.Get("user")
 	userClaim := u.(*helper.UserClaims)
-	sb := &models.SubmitBasic{
-		Identity:        helper.GetUUID(),
-		ProblemIdentity: problemIdentity,
-		UserIdentity:    userClaim.Identity,
-		Path:            path,
+	// 报名的用户在比赛进行期间的提交计入比赛
+	contestIdentity, err := models.GetContestIdentity(problemIdentity, userClaim.Identity, time.Now())
+	if err != nil {
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  "get contest err:" + err.Error(),
+		})
+		return
 	}
-
-	// 代码判断
-	pb := new(models.ProblemBasic)
-	err = models.DB.Where("identity = ?", problemIdentity).Preload("TestCase").First(pb).Error
+	// 代码保存
+	path, err := helper.CodeSave(code, lang.SourceFile)
 	if err != nil {
 		ctx.JSON(http.StatusOK, gin.H{
 			"code": -1,
@@ -111,88 +280,25 @@ func Submit(ctx *gin.Context) {
 		})
 		return
 	}
-	WA := make(chan int)  // 错误答案的情况
-	OOM := make(chan int) // 超内存
-	CE := make(chan int)  // 编译错误
-	passCount := 0
-	var lock sync.Mutex // 定义互斥锁
-	// 提示信息
-	var msg string
-
-	for _, testcode := range pb.TestCase {
-		go func() {
-			// 通过协程执行测试
-			cmd := exec.Command("go", "run", path)
-			var out, stderr bytes.Buffer
-			cmd.Stderr = &stderr
-			cmd.Stdout = &out
-
-			stdinPipe, err := cmd.StdinPipe()
-			if err != nil {
-				log.Fatalln(err)
-			}
-			io.WriteString(stdinPipe, testcode.Input)
-			// 根据测试的输入案例进行运行拿到输出结果和标准输出结果是否匹配
-			var bm runtime.MemStats
-			runtime.ReadMemStats(&bm)
-			if err := cmd.Run(); err != nil {
-				log.Println(err, stderr.String())
-				if err.Error() == "exit status 2" {
-					msg = stderr.String()
-					CE <- 1
-					return
-				}
-			}
-			var em runtime.MemStats
-			runtime.ReadMemStats(&em)
-			// 答案错误情况
-			if testcode.Output != out.String() {
-				msg = "答案错误"
-				WA <- 1
-				return
-			}
-			// 运行超内存情况
-			if em.Alloc/1024-bm.Alloc/1024 > uint64(pb.MaxMem) {
-				msg = "运行超内存"
-				OOM <- 1
-				return
-			}
-			lock.Lock()
-			passCount++
-
-			lock.Unlock()
-
-		}()
-	}
-
-	select {
-	// -1-待判断，1-正确，2-错误，3-超时，4-超内存， 5-编译错误
-	case <-WA:
-		sb.Status = 2
-	case <-OOM:
-		sb.Status = 4
-	case <-CE:
-		sb.Status = 5
-	case <-time.After(time.Millisecond * time.Duration(pb.MaxRuntime)):
-		if passCount == len(pb.TestCase) {
-			sb.Status = 1
-			msg = "答案正确"
-		} else {
-			sb.Status = 3
-			msg = "运行超时"
-		}
+	// -1-待判断，1-正确，2-错误，3-超时，4-超内存， 5-编译错误，6-系统错误，7-运行错误(非法系统调用)，
+	// 8-运行错误(非0退出码或被信号终止)，9-输出超限
+	sb := &models.SubmitBasic{
+		Identity:        helper.GetUUID(),
+		ProblemIdentity: problemIdentity,
+		UserIdentity:    userClaim.Identity,
+		ContestIdentity: contestIdentity,
+		Path:            path,
+		Language:        lang.Name,
+		Status:          judge.StatusPending,
 	}
 
 	if err = models.DB.Transaction(func(tx *gorm.DB) error {
 		err = tx.Create(sb).Error
 		if err != nil {
-			return errors.New("userbasic create err:" + err.Error())
+			return errors.New("submitbasic create err:" + err.Error())
 		}
 		m := make(map[string]interface{})
 		m["submit_num"] = gorm.Expr("submit_num + ?", 1)
-		if sb.Status == 1 {
-			m["pass_num"] = gorm.Expr("pass_num + ?", 1)
-		}
 		// 更新userbasic
 		err = tx.Model(new(models.UserBasic)).Where("identity = ?", userClaim.Identity).Updates(m).Error
 		if err != nil {
@@ -213,11 +319,21 @@ func Submit(ctx *gin.Context) {
 		return
 	}
 
+	// 放入对应编程语言的判题队列，由判题节点完成判断，失败时提交判为系统错误
+	err = dispatch.Submit(ctx, sb.Identity)
+	if err != nil {
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  "push submit err:" + err.Error(),
+		})
+		return
+	}
+
 	ctx.JSON(http.StatusOK, gin.H{
 		"code": 200,
-		"msg": map[string]interface{}{
-			"status": sb.Status,
-			"msg":    msg,
+		"data": map[string]interface{}{
+			"identity": sb.Identity,
+			"status":   sb.Status,
 		},
 	})
 }
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"gin_gorm_oj/archive"
+	"gin_gorm_oj/helper"
+	"gin_gorm_oj/models"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ProblemTestCaseUpload
+// @Tags 管理员私有方法
+// @Summary 上传测试用例压缩包
+// @Description 压缩包中的测试用例为成对的输入和标准输出文件，如1.in和1.out（或1.ans），按照文件名排序
+// @Param authorization header string true "authorization"
+// @Param identity formData string true "问题唯一标识"
+// @Param mode formData string false "replace替换原有的测试用例（默认），append追加到原有的测试用例之后"
+// @Param samples formData string false "作为样例的测试用例的文件名（不含扩展名），逗号分隔，如1,2"
+// @Param file formData file true "测试用例zip压缩包"
+// @Success 200 {string} json "{"code":"200","msg":"","data":""}"
+// @Router /admin/problem-test-case-upload [post]
+func ProblemTestCaseUpload(ctx *gin.Context) {
+	identity := ctx.PostForm("identity")
+	mode := ctx.DefaultPostForm("mode", "replace")
+	if identity == "" || (mode != "replace" && mode != "append") {
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  "参数不正确",
+		})
+		return
+	}
+	var cnt int64
+	err := models.DB.Model(new(models.ProblemBasic)).Where("identity = ?", identity).Count(&cnt).Error
+	if err != nil || cnt == 0 {
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  "当前问题不存在",
+		})
+		return
+	}
+	file, err := ctx.FormFile("file")
+	if err != nil {
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  "读取压缩包失败:" + err.Error(),
+		})
+		return
+	}
+	f, err := file.Open()
+	if err != nil {
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  "读取压缩包失败:" + err.Error(),
+		})
+		return
+	}
+	defer f.Close()
+	zr, err := zip.NewReader(f, file.Size)
+	if err != nil {
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  "读取压缩包失败:" + err.Error(),
+		})
+		return
+	}
+	files, err := archive.TestCases(zr.File, "")
+	if err == nil && ctx.PostForm("samples") != "" {
+		err = archive.MarkSamples(files, strings.Split(ctx.PostForm("samples"), ","))
+	}
+	if err != nil {
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  err.Error(),
+		})
+		return
+	}
+	tcs, err := saveTestCaseFiles(ctx.Request.Context(), identity, files)
+	if err != nil {
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  "测试用例保存失败:" + err.Error(),
+		})
+		return
+	}
+
+	// 替换时原有的测试数据在保存成功后删除
+	oldTcs := make([]*models.TestCase, 0)
+	err = models.DB.Transaction(func(tx *gorm.DB) error {
+		if mode == "replace" {
+			err := tx.Where("problem_identity = ?", identity).Find(&oldTcs).Error
+			if err != nil {
+				return err
+			}
+			err = tx.Where("problem_identity = ?", identity).Delete(new(models.TestCase)).Error
+			if err != nil {
+				return err
+			}
+		}
+		return tx.Create(&tcs).Error
+	})
+	if err != nil {
+		models.DeleteTestCaseData(ctx.Request.Context(), tcs)
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  "测试用例保存失败:" + err.Error(),
+		})
+		return
+	}
+	models.DeleteTestCaseData(ctx.Request.Context(), oldTcs)
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "上传成功",
+		"data": gin.H{
+			"count": len(tcs),
+		},
+	})
+}
+
+// saveTestCaseFiles 将压缩包中的测试用例保存到存储中，出错时删除已经保存的数据
+func saveTestCaseFiles(ctx context.Context, problemIdentity string, files []*archive.TestCaseFile) ([]*models.TestCase, error) {
+	tcs := make([]*models.TestCase, 0, len(files))
+	for _, file := range files {
+		tc := &models.TestCase{Identity: helper.GetUUID(), ProblemIdentity: problemIdentity, IsSample: file.Sample}
+		err := saveTestCaseFile(ctx, tc, file)
+		if err != nil {
+			models.DeleteTestCaseData(ctx, tcs)
+			return nil, err
+		}
+		tcs = append(tcs, tc)
+	}
+	return tcs, nil
+}
+
+func saveTestCaseFile(ctx context.Context, tc *models.TestCase, file *archive.TestCaseFile) error {
+	in, err := file.Input.Open()
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := file.Output.Open()
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	return tc.SaveData(ctx, in, int64(file.Input.UncompressedSize64), out, int64(file.Output.UncompressedSize64))
+}
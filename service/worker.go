@@ -0,0 +1,46 @@
+package service
+
+import (
+	"gin_gorm_oj/dispatch"
+	"gin_gorm_oj/judge"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetWorkerList
+// @Tags 管理员私有方法
+// @Summary 判题节点列表
+// @Description 返回在线的判题节点（最近15秒内有心跳）和各编程语言等待处理的任务数
+// @Param authorization header string true "authorization"
+// @Success 200 {string} json "{"code":"200","data":""}"
+// @Router /admin/worker-list [get]
+func GetWorkerList(ctx *gin.Context) {
+	list, err := dispatch.Queue.Workers(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  "Get workerList Error:" + err.Error(),
+		})
+		return
+	}
+	languages := make([]string, 0, len(judge.Languages))
+	for name := range judge.Languages {
+		languages = append(languages, name)
+	}
+	pending, err := dispatch.Queue.Len(ctx, languages)
+	if err != nil {
+		ctx.JSON(http.StatusOK, gin.H{
+			"code": -1,
+			"msg":  "Get queue length Error:" + err.Error(),
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"data": map[string]interface{}{
+			"list":    list,
+			"pending": pending,
+		},
+	})
+}
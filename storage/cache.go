@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Cache 判题节点上的测试数据缓存，按照sha256保存，内容相同的对象只下载一次
+type Cache struct {
+	Storage Storage
+	Dir     string
+
+	mu sync.Mutex
+	// 正在下载的对象，同一个对象同时只下载一次
+	loading map[string]*sync.Mutex
+}
+
+// File 返回对象在本地缓存中的路径，不存在时从存储下载并校验sha256
+func (c *Cache) File(ctx context.Context, key, sum string) (string, error) {
+	if len(sum) != sha256.Size*2 {
+		return "", fmt.Errorf("storage: invalid sha256 %q for %s", sum, key)
+	}
+	p := filepath.Join(c.Dir, sum[:2], sum)
+	if _, err := os.Stat(p); err == nil {
+		return p, nil
+	}
+	l := c.lock(sum)
+	l.Lock()
+	defer l.Unlock()
+	if _, err := os.Stat(p); err == nil {
+		return p, nil
+	}
+	if err := c.download(ctx, key, sum, p); err != nil {
+		return "", err
+	}
+	return p, nil
+}
+
+func (c *Cache) lock(sum string) *sync.Mutex {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.loading == nil {
+		c.loading = make(map[string]*sync.Mutex)
+	}
+	l, ok := c.loading[sum]
+	if !ok {
+		l = new(sync.Mutex)
+		c.loading[sum] = l
+	}
+	return l
+}
+
+// download 下载到临时文件，校验通过后再重命名到缓存路径
+func (c *Cache) download(ctx context.Context, key, sum, p string) error {
+	r, err := c.Storage.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(p), ".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	h := sha256.New()
+	_, err = io.Copy(io.MultiWriter(f, h), r)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); got != sum {
+		return errors.New("storage: checksum mismatch for " + key + ", got " + got)
+	}
+	if err := os.Chmod(f.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), p)
+}
@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"gin_gorm_oj/define"
+	"io"
+)
+
+// ErrNotExist 对象不存在
+var ErrNotExist = errors.New("storage: object does not exist")
+
+// Storage 测试数据等文件的存储，key为以/分隔的相对路径
+type Storage interface {
+	// Put 保存对象，size为r的长度
+	Put(ctx context.Context, key string, r io.Reader, size int64) error
+	// Get 读取对象，对象不存在时返回ErrNotExist
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete 删除对象，对象不存在时不返回错误
+	Delete(ctx context.Context, key string) error
+}
+
+// Default 默认的存储，根据define.StorageType选择本地目录或S3兼容的对象存储
+var Default = New()
+
+// DefaultCache 判题节点上测试数据的本地缓存
+var DefaultCache = &Cache{Storage: Default, Dir: define.StorageCacheDir}
+
+// New 根据配置创建存储
+func New() Storage {
+	if define.StorageType == "s3" {
+		return &S3{
+			Endpoint:  define.S3Endpoint,
+			Region:    define.S3Region,
+			Bucket:    define.S3Bucket,
+			AccessKey: define.S3AccessKey,
+			SecretKey: define.S3SecretKey,
+		}
+	}
+	return &Local{Dir: define.StorageDir}
+}
@@ -0,0 +1,162 @@
+package test
+
+import (
+	"archive/zip"
+	"bytes"
+	"gin_gorm_oj/archive"
+	"strings"
+	"testing"
+)
+
+// zipFiles 创建包含files的压缩包
+func zipFiles(t *testing.T, files map[string]string) *zip.Reader {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, data := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(data))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return zr
+}
+
+func TestArchiveTestCases(t *testing.T) {
+	zr := zipFiles(t, map[string]string{
+		"10.in":             "10",
+		"10.out":            "10",
+		"2.in":              "2",
+		"2.ans":             "2",
+		"1.in":              "1",
+		"1.out":             "1",
+		"readme.txt":        "",
+		"__MACOSX/._1.in":   "",
+		"tests/3.in":        "3",
+		"tests/3.out":       "3",
+		"tests/checker.cpp": "",
+	})
+	tcs, err := archive.TestCases(zr.File, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := ""
+	for _, tc := range tcs {
+		names += tc.Name + " "
+	}
+	if names != "1 2 10 tests/3 " {
+		t.Fatalf("names = %q", names)
+	}
+	tcs, err = archive.TestCases(zr.File, "tests/")
+	if err != nil || len(tcs) != 1 || tcs[0].Name != "3" {
+		t.Fatalf("tests/ = %v, %v", tcs, err)
+	}
+
+	if err := archive.MarkSamples(tcs, []string{" 3", "4"}); err == nil || !tcs[0].Sample {
+		t.Fatalf("mark samples err = %v", err)
+	}
+
+	bad := []map[string]string{
+		{"1.in": "1"},
+		{"1.out": "1"},
+		{"1.in": "1", "1.out": "1", "1.ans": "1"},
+		{"readme.txt": ""},
+	}
+	for _, files := range bad {
+		if _, err := archive.TestCases(zipFiles(t, files).File, ""); err == nil {
+			t.Errorf("%v: no error", files)
+		}
+	}
+}
+
+func TestArchiveOpen(t *testing.T) {
+	p := &archive.Problem{
+		Title:       "A+B",
+		MaxRuntime:  1000,
+		MaxMem:      65536,
+		CompareMode: "token",
+		Categories:  []string{"数学"},
+		Samples:     []string{"2"},
+		Checker:     &archive.Program{Language: "python", Source: "checker/main.py"},
+	}
+	meta, err := p.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 题目包在压缩包的一个目录中
+	pkg, err := archive.Open(zipFiles(t, map[string]string{
+		"a/problem.yaml":    string(meta),
+		"a/statement.md":    "# A+B",
+		"a/tests/1.in":      "1 2",
+		"a/tests/1.out":     "3",
+		"a/tests/2.in":      "2 3",
+		"a/tests/2.ans":     "5",
+		"a/checker/main.py": "print()",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkg.Title != "A+B" || pkg.MaxMem != 65536 || pkg.Content != "# A+B" || len(pkg.Tests) != 2 || pkg.Categories[0] != "数学" {
+		t.Fatalf("pkg = %+v", pkg)
+	}
+	if pkg.Tests[0].Sample || !pkg.Tests[1].Sample {
+		t.Fatal("sample not marked")
+	}
+	if code, err := pkg.Source(pkg.Checker); err != nil || string(code) != "print()" {
+		t.Fatalf("checker = %q, %v", code, err)
+	}
+	if _, err := archive.Open(zipFiles(t, map[string]string{"problem.yaml": string(meta), "tests/1.in": "1"})); err == nil {
+		t.Fatal("missing checker and output not detected")
+	}
+
+	polygon := `<?xml version="1.0" encoding="utf-8" standalone="no"?>
+<problem revision="3" short-name="a-plus-b">
+  <names><name language="english" value="A + B"/></names>
+  <statements><statement charset="UTF-8" language="english" path="statements/english/problem.tex" type="application/x-tex"/></statements>
+  <judging>
+    <testset name="tests">
+      <time-limit>2000</time-limit>
+      <memory-limit>268435456</memory-limit>
+      <test-count>2</test-count>
+      <input-path-pattern>tests/%02d</input-path-pattern>
+      <answer-path-pattern>tests/%02d.a</answer-path-pattern>
+      <tests><test method="manual" sample="true"/><test method="manual"/></tests>
+    </testset>
+  </judging>
+  <assets><checker name="std::rcmp6.cpp" type="testlib"><source path="files/check.cpp" type="cpp.g++17"/></checker></assets>
+  <tags><tag value="math"/></tags>
+</problem>`
+	files := map[string]string{
+		"problem.xml":                           polygon,
+		"statement-sections/english/legend.tex": "Add two numbers.",
+		"statement-sections/english/input.tex":  "Two integers.",
+		"tests/01":                              "1 2",
+		"tests/01.a":                            "3",
+		"tests/02":                              "2 3",
+		"tests/02.a":                            "5",
+	}
+	pkg, err = archive.Open(zipFiles(t, files))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkg.Title != "A + B" || pkg.MaxRuntime != 2000 || pkg.MaxMem != 262144 || pkg.CompareMode != "float" ||
+		pkg.AbsEpsilon != 1e-6 || len(pkg.Tests) != 2 || !pkg.Tests[0].Sample || pkg.Tests[1].Sample || pkg.Categories[0] != "math" ||
+		!strings.Contains(pkg.Content, "Add two numbers.") || !strings.Contains(pkg.Content, "Two integers.") {
+		t.Fatalf("pkg = %+v", pkg)
+	}
+	delete(files, "tests/02.a")
+	if _, err := archive.Open(zipFiles(t, files)); err == nil {
+		t.Fatal("missing test not detected")
+	}
+	files["problem.xml"] = strings.Replace(polygon, "std::rcmp6.cpp", "check.cpp", 1)
+	if _, err := archive.Open(zipFiles(t, files)); err == nil {
+		t.Fatal("custom checker not rejected")
+	}
+}
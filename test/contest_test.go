@@ -0,0 +1,153 @@
+package test
+
+import (
+	"gin_gorm_oj/helper"
+	"gin_gorm_oj/models"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestContestTime(t *testing.T) {
+	start := time.Date(2022, 5, 1, 14, 0, 0, 0, time.Local)
+	c := &models.ContestBasic{StartAt: start, EndAt: start.Add(time.Hour * 2)}
+	cases := []struct {
+		now            time.Time
+		started, ended bool
+	}{
+		{start.Add(-time.Second), false, false},
+		{start, true, false},
+		{start.Add(time.Hour), true, false},
+		{start.Add(time.Hour * 2), true, true},
+	}
+	for _, tc := range cases {
+		if c.Started(tc.now) != tc.started || c.Ended(tc.now) != tc.ended {
+			t.Errorf("%v: started = %v, ended = %v", tc.now, c.Started(tc.now), c.Ended(tc.now))
+		}
+	}
+}
+
+// createContest 创建包含问题的比赛，users为报名的用户
+func createContest(t *testing.T, start, end time.Time, problem string, users ...string) *models.ContestBasic {
+	t.Helper()
+	cb := &models.ContestBasic{Identity: helper.GetUUID(), Title: "contest test", StartAt: start, EndAt: end}
+	if err := models.DB.Create(cb).Error; err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		deleteRows(t, new(models.ContestUser), "contest_identity = ?", cb.Identity)
+		deleteRows(t, new(models.ContestProblem), "contest_identity = ?", cb.Identity)
+		deleteRows(t, new(models.ContestBasic), "identity = ?", cb.Identity)
+	})
+	cp := &models.ContestProblem{ContestIdentity: cb.Identity, ProblemIdentity: problem, Letter: "A"}
+	if err := models.DB.Create(cp).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, user := range users {
+		if err := models.DB.Create(&models.ContestUser{ContestIdentity: cb.Identity, UserIdentity: user}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	return cb
+}
+
+// createProblem 创建标题唯一的问题，用于按照关键字查询问题列表
+func createProblem(t *testing.T) *models.ProblemBasic {
+	t.Helper()
+	pb := &models.ProblemBasic{Identity: helper.GetUUID(), Title: "contest test " + helper.GetUUID(), MaxRuntime: 1000, MaxMem: 64}
+	if err := models.DB.Create(pb).Error; err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { deleteRows(t, new(models.ProblemBasic), "identity = ?", pb.Identity) })
+	return pb
+}
+
+func TestContestHiddenProblem(t *testing.T) {
+	requireDB(t, new(models.ProblemBasic), new(models.ProblemCategory), new(models.CategoryBasic), new(models.TestCase),
+		new(models.LanguageLimit), new(models.ContestBasic), new(models.ContestProblem), new(models.ContestUser))
+	pb := createProblem(t)
+	now := time.Now()
+	cb := createContest(t, now.Add(time.Hour), now.Add(time.Hour*3), pb.Identity)
+	token := testToken(t, helper.GetUUID(), 0)
+
+	check := func(hidden bool) {
+		t.Helper()
+		got, err := models.IsProblemHidden(pb.Identity)
+		if err != nil || got != hidden {
+			t.Fatalf("IsProblemHidden = %v, %v, want %v", got, err, hidden)
+		}
+		res := callAPI(t, http.MethodGet, "/problem-list?keyword="+url.QueryEscape(pb.Title), nil, "", "")
+		data := new(struct {
+			List  []*models.ProblemBasic `json:"list"`
+			Count int64                  `json:"count"`
+		})
+		decodeData(t, res, data)
+		if hidden != (data.Count == 0 && len(data.List) == 0) {
+			t.Fatalf("problem-list: hidden = %v, %s", hidden, res.Data)
+		}
+		if !hidden && data.List[0].Identity != pb.Identity {
+			t.Fatalf("problem-list: %s", res.Data)
+		}
+		res = callAPI(t, http.MethodGet, "/problem-detail?identity="+pb.Identity, nil, "", "")
+		if hidden != (res.Code != 200) {
+			t.Fatalf("problem-detail: hidden = %v, %d %s", hidden, res.Code, res.Msg)
+		}
+		// 不能通过自定义输入运行探测未公开的问题，公开的问题需要判题节点运行，这里不检查
+		if hidden {
+			form := url.Values{"problem_identity": {pb.Identity}, "code": {"package main\n"}}
+			res = callAPI(t, http.MethodPost, "/user/run", strings.NewReader(form.Encode()), "application/x-www-form-urlencoded", token)
+			if res.Code != -1 || res.Msg != "当前问题不存在" {
+				t.Fatalf("run: %d %s", res.Code, res.Msg)
+			}
+		}
+	}
+	// 比赛开始前问题不公开，开始后公开
+	check(true)
+	err := models.DB.Model(cb).Updates(map[string]interface{}{"start_at": now.Add(-time.Hour), "end_at": now.Add(time.Hour)}).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(false)
+	// 比赛改为未开始时问题重新隐藏，比赛删除后问题公开
+	if err := models.DB.Model(cb).Update("start_at", now.Add(time.Hour)).Error; err != nil {
+		t.Fatal(err)
+	}
+	check(true)
+	if err := models.DB.Delete(cb).Error; err != nil {
+		t.Fatal(err)
+	}
+	check(false)
+}
+
+func TestGetContestIdentity(t *testing.T) {
+	requireDB(t, new(models.ProblemBasic), new(models.ContestBasic), new(models.ContestProblem), new(models.ContestUser))
+	pb := createProblem(t)
+	user, other := helper.GetUUID(), helper.GetUUID()
+	start := time.Now().Truncate(time.Second)
+	first := createContest(t, start, start.Add(time.Hour*2), pb.Identity, user)
+	second := createContest(t, start.Add(time.Hour), start.Add(time.Hour*3), pb.Identity, user, other)
+	cases := []struct {
+		name, user string
+		now        time.Time
+		want       string
+	}{
+		{"before start", user, start.Add(-time.Second), ""},
+		{"running", user, start.Add(time.Minute), first.Identity},
+		{"earliest of overlapping", user, start.Add(time.Hour + time.Minute), first.Identity},
+		{"after first ended", user, start.Add(time.Hour * 2), second.Identity},
+		{"not registered", other, start.Add(time.Minute), ""},
+		{"registered later contest", other, start.Add(time.Hour + time.Minute), second.Identity},
+		{"after all ended", user, start.Add(time.Hour * 3), ""},
+	}
+	for _, tc := range cases {
+		got, err := models.GetContestIdentity(pb.Identity, tc.user, tc.now)
+		if err != nil || got != tc.want {
+			t.Errorf("%s: got %q, %v, want %q", tc.name, got, err, tc.want)
+		}
+	}
+	if got, err := models.GetContestIdentity(helper.GetUUID(), user, start.Add(time.Minute)); err != nil || got != "" {
+		t.Errorf("other problem: got %q, %v", got, err)
+	}
+}
@@ -0,0 +1,88 @@
+package test
+
+import (
+	"encoding/json"
+	"gin_gorm_oj/helper"
+	"gin_gorm_oj/models"
+	"gin_gorm_oj/router"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// requireDB 连接不到数据库时跳过测试，连接成功时创建测试用到的表
+func requireDB(t *testing.T, tables ...interface{}) {
+	t.Helper()
+	if models.DB == nil {
+		t.Skip("database is not available")
+	}
+	sqlDB, err := models.DB.DB()
+	if err == nil {
+		err = sqlDB.Ping()
+	}
+	if err != nil {
+		t.Skip("database is not available:", err)
+	}
+	if err := models.DB.AutoMigrate(tables...); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// deleteRows 删除测试中创建的数据
+func deleteRows(t *testing.T, model interface{}, query string, args ...interface{}) {
+	t.Helper()
+	if err := models.DB.Unscoped().Where(query, args...).Delete(model).Error; err != nil {
+		t.Error(err)
+	}
+}
+
+// testToken 生成测试用户的token
+func testToken(t *testing.T, identity string, isAdmin int) string {
+	t.Helper()
+	token, err := helper.GenerateToken(identity, identity, isAdmin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// apiResponse 接口返回的json
+type apiResponse struct {
+	Code int             `json:"code"`
+	Msg  string          `json:"msg"`
+	Data json.RawMessage `json:"data"`
+}
+
+// serve 通过路由处理请求，返回响应
+func serve(req *http.Request, token string) *httptest.ResponseRecorder {
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+	w := httptest.NewRecorder()
+	router.Router().ServeHTTP(w, req)
+	return w
+}
+
+// callAPI 调用接口并解析返回的json
+func callAPI(t *testing.T, method, target string, body io.Reader, contentType, token string) *apiResponse {
+	t.Helper()
+	req := httptest.NewRequest(method, target, body)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	w := serve(req, token)
+	res := new(apiResponse)
+	if err := json.Unmarshal(w.Body.Bytes(), res); err != nil {
+		t.Fatalf("%s %s: %v, body = %s", method, target, err, w.Body.String())
+	}
+	return res
+}
+
+// decodeData 解析接口返回的data
+func decodeData(t *testing.T, res *apiResponse, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(res.Data, v); err != nil {
+		t.Fatalf("decode data %s: %v", res.Data, err)
+	}
+}
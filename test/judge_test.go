@@ -0,0 +1,808 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"gin_gorm_oj/judge"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// 在隔离环境中运行用户程序的判题引擎
+var sandboxJudge judge.Judge
+
+func TestMain(m *testing.M) {
+	// 编译用户程序的启动进程
+	dir, err := os.MkdirTemp("", "judge")
+	if err != nil {
+		log.Fatalln(err)
+	}
+	initPath := filepath.Join(dir, "judge-init")
+	out, err := exec.Command("go", "build", "-o", initPath, "../cmd/judge-init").CombinedOutput()
+	if err != nil {
+		log.Fatalln(err, string(out))
+	}
+	judge.Default = &judge.LocalJudge{InitPath: initPath}
+	sandboxJudge = &judge.LocalJudge{InitPath: initPath, Sandbox: true, MaxProcs: 256}
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// fakeJudge 按照输入直接给出判断结果
+type fakeJudge struct {
+	compileErr error
+	status     map[string]int
+}
+
+func (j *fakeJudge) Compile(ctx context.Context, lang *judge.Language, path string) (*judge.Program, error) {
+	if j.compileErr != nil {
+		return nil, j.compileErr
+	}
+	return &judge.Program{Dir: filepath.Dir(path), Language: lang}, nil
+}
+
+func (j *fakeJudge) Run(ctx context.Context, prog *judge.Program, tc *judge.TestCase, limit judge.Limit, cmp judge.Compare) *judge.CaseResult {
+	return &judge.CaseResult{Identity: tc.Identity, Status: j.status[tc.Identity]}
+}
+
+func TestJudgeDo(t *testing.T) {
+	s := &judge.Submission{
+		Limit: judge.Limit{MaxRuntime: 1000, MaxMem: 1024},
+		TestCases: []*judge.TestCase{
+			{Identity: "1"},
+			{Identity: "2"},
+		},
+	}
+	j := &fakeJudge{status: map[string]int{"1": judge.StatusAccepted, "2": judge.StatusAccepted}}
+	if res := judge.Do(context.Background(), j, s); res.Status != judge.StatusAccepted || len(res.Cases) != 2 {
+		t.Fatalf("accepted submission judged as %+v", res)
+	}
+	j.status["2"] = judge.StatusWrongAnswer
+	if res := judge.Do(context.Background(), j, s); res.Status != judge.StatusWrongAnswer {
+		t.Fatalf("wrong answer judged as %+v", res)
+	}
+	// 多个测试用例失败时按照状态的优先级汇总
+	j.status["1"] = judge.StatusTimeLimit
+	if res := judge.Do(context.Background(), j, s); res.Status != judge.StatusTimeLimit {
+		t.Fatalf("time limit judged as %+v", res)
+	}
+	j.compileErr = &judge.CompileError{Msg: "syntax error"}
+	if res := judge.Do(context.Background(), j, s); res.Status != judge.StatusCompileError || res.Msg != "syntax error" {
+		t.Fatalf("compile error judged as %+v", res)
+	}
+}
+
+func TestJudgeDoProgress(t *testing.T) {
+	s := &judge.Submission{Limit: judge.Limit{MaxRuntime: 1000, MaxMem: 1024}}
+	status := make(map[string]int)
+	for i := 0; i < 20; i++ {
+		id := fmt.Sprint(i)
+		s.TestCases = append(s.TestCases, &judge.TestCase{Identity: id})
+		status[id] = judge.StatusAccepted
+	}
+	status["7"] = judge.StatusWrongAnswer
+	// Progress不会同时调用，不需要加锁
+	seen := make(map[int]bool)
+	s.Progress = func(i int, c *judge.CaseResult) {
+		if seen[i] || c.Identity != s.TestCases[i].Identity {
+			t.Errorf("progress %d: %+v", i, c)
+		}
+		seen[i] = true
+	}
+	res := judge.Do(context.Background(), &fakeJudge{status: status}, s)
+	if res.Status != judge.StatusWrongAnswer || len(seen) != len(s.TestCases) {
+		t.Fatalf("status = %d, progress = %d", res.Status, len(seen))
+	}
+}
+
+// parallelJudge 记录同时运行的测试用例数的最大值
+type parallelJudge struct {
+	fakeJudge
+	running, max int32
+}
+
+func (j *parallelJudge) Run(ctx context.Context, prog *judge.Program, tc *judge.TestCase, limit judge.Limit, cmp judge.Compare) *judge.CaseResult {
+	n := atomic.AddInt32(&j.running, 1)
+	defer atomic.AddInt32(&j.running, -1)
+	for {
+		m := atomic.LoadInt32(&j.max)
+		if n <= m || atomic.CompareAndSwapInt32(&j.max, m, n) {
+			break
+		}
+	}
+	time.Sleep(time.Millisecond * 10)
+	return &judge.CaseResult{Identity: tc.Identity, Status: judge.StatusAccepted}
+}
+
+func TestJudgeDoParallel(t *testing.T) {
+	s := &judge.Submission{Limit: judge.Limit{MaxRuntime: 1000, MaxMem: 1024}}
+	for i := 0; i < 12; i++ {
+		s.TestCases = append(s.TestCases, &judge.TestCase{Identity: fmt.Sprint(i)})
+	}
+	for parallel, want := range map[int]int32{0: 1, 1: 1, 3: 3} {
+		j := new(parallelJudge)
+		s.Parallel = parallel
+		if res := judge.Do(context.Background(), j, s); res.Status != judge.StatusAccepted {
+			t.Fatalf("parallel %d: status = %d", parallel, res.Status)
+		}
+		if j.max != want {
+			t.Fatalf("parallel %d: %d test cases ran at the same time, want %d", parallel, j.max, want)
+		}
+	}
+}
+
+// copyCode 将代码复制到临时目录，避免编译产物写入仓库
+func copyCode(t *testing.T, src string) string {
+	code, err := os.ReadFile(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "main.go")
+	if err := os.WriteFile(path, code, 0666); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLocalJudge(t *testing.T) {
+	s := &judge.Submission{
+		Path:  copyCode(t, "../code/code-user/main.go"),
+		Limit: judge.Limit{MaxRuntime: 1000, MaxMem: 1024 * 1024},
+		TestCases: []*judge.TestCase{
+			{Identity: "1", Input: "23 11\n", Output: "34\n"},
+			{Identity: "2", Input: "1 2\n", Output: "4\n"},
+		},
+	}
+	res := judge.Do(context.Background(), judge.Default, s)
+	if res.Status != judge.StatusWrongAnswer {
+		t.Fatalf("status = %d, msg = %s", res.Status, res.Msg)
+	}
+	if res.Cases[0].Status != judge.StatusAccepted {
+		t.Fatalf("case 1 status = %d, msg = %s", res.Cases[0].Status, res.Cases[0].Msg)
+	}
+}
+
+func TestLocalJudgeCompileError(t *testing.T) {
+	path := writeCode(t, "package main\n\nfunc main() {\n\tundefined()\n}\n")
+	lang, _ := judge.GetLanguage("go")
+	_, err := judge.Default.Compile(context.Background(), lang, path)
+	ce, ok := err.(*judge.CompileError)
+	if !ok {
+		t.Fatalf("err = %v, want compile error", err)
+	}
+	if !strings.Contains(ce.Msg, "undefined") {
+		t.Fatalf("compile error msg = %q", ce.Msg)
+	}
+}
+
+func TestLocalJudgeCompileErrorTruncated(t *testing.T) {
+	lang, _ := judge.GetLanguage("c")
+	var code strings.Builder
+	for i := 0; i < 500; i++ {
+		fmt.Fprintf(&code, "int v%d = undefined%d;\n", i, i)
+	}
+	code.WriteString("int main() { return 0; }\n")
+	path := writeSource(t, lang.SourceFile, code.String())
+	_, err := judge.Default.Compile(context.Background(), lang, path)
+	ce, ok := err.(*judge.CompileError)
+	if !ok {
+		t.Fatalf("err = %v, want compile error", err)
+	}
+	if !strings.Contains(ce.Msg, "undefined0") || len(ce.Msg) > 4096 {
+		t.Fatalf("compile error msg length = %d", len(ce.Msg))
+	}
+}
+
+func TestLocalJudgeCompileIsolation(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("sandbox is only supported on linux")
+	}
+	// 代码目录以外的本机文件不能被编译器读取，编译错误信息中不会出现文件内容
+	secret := filepath.Join(t.TempDir(), "secret.h")
+	if err := os.WriteFile(secret, []byte("judge host secret\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	lang, _ := judge.GetLanguage("c")
+	for _, include := range []string{secret, "/etc/passwd"} {
+		path := writeSource(t, lang.SourceFile, fmt.Sprintf("#include \"%s\"\nint main() { return 0; }\n", include))
+		_, err := sandboxJudge.Compile(context.Background(), lang, path)
+		ce, ok := err.(*judge.CompileError)
+		if !ok {
+			t.Fatalf("%s: err = %v, want compile error", include, err)
+		}
+		if strings.Contains(ce.Msg, "judge host secret") || strings.Contains(ce.Msg, "root:") || !strings.Contains(ce.Msg, "No such file") {
+			t.Fatalf("%s: compile error msg = %s", include, ce.Msg)
+		}
+	}
+}
+
+// writeCode 将go代码写入临时目录
+func writeCode(t *testing.T, code string) string {
+	return writeSource(t, "main.go", code)
+}
+
+// writeSource 将代码写入临时目录
+func writeSource(t *testing.T, name, code string) string {
+	dir := t.TempDir()
+	// 隔离环境中用户程序以nobody运行
+	if err := os.Chmod(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(code), 0666); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLocalJudgeUsage(t *testing.T) {
+	path := writeCode(t, `package main
+
+import "fmt"
+
+func main() {
+	b := make([]byte, 64<<20)
+	for i := range b {
+		b[i] = 1
+	}
+	fmt.Println(len(b))
+}
+`)
+	s := &judge.Submission{
+		Path:      path,
+		Limit:     judge.Limit{MaxRuntime: 1000, MaxMem: 16 * 1024},
+		TestCases: []*judge.TestCase{{Identity: "1", Output: "67108864\n"}},
+	}
+	res := judge.Do(context.Background(), judge.Default, s)
+	if res.Status != judge.StatusMemoryLimit {
+		t.Fatalf("status = %d, msg = %s", res.Status, res.Msg)
+	}
+	if res.Mem < 64*1024 {
+		t.Fatalf("mem = %dKB, want at least 64MB", res.Mem)
+	}
+}
+
+func TestLocalJudgeRlimit(t *testing.T) {
+	path := writeCode(t, `package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func main() {
+	for i := 0; i < 100; i++ {
+		if _, err := os.Open("/dev/null"); err != nil {
+			fmt.Println("limited")
+			return
+		}
+	}
+	fmt.Println("unlimited")
+}
+`)
+	s := &judge.Submission{
+		Path:      path,
+		Limit:     judge.Limit{MaxRuntime: 1000, MaxMem: 64 * 1024},
+		TestCases: []*judge.TestCase{{Identity: "1", Output: "limited\n"}},
+	}
+	res := judge.Do(context.Background(), judge.Default, s)
+	if res.Status != judge.StatusAccepted {
+		t.Fatalf("status = %d, msg = %s", res.Status, res.Msg)
+	}
+}
+
+func TestLocalJudgeSandbox(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("sandbox is only supported on linux")
+	}
+	s := &judge.Submission{
+		Path:      writeCode(t, "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tvar a, b int\n\tfmt.Scanln(&a, &b)\n\tfmt.Println(a + b)\n}\n"),
+		Limit:     judge.Limit{MaxRuntime: 1000, MaxMem: 64 * 1024},
+		TestCases: []*judge.TestCase{{Identity: "1", Input: "23 11\n", Output: "34\n"}},
+	}
+	if res := judge.Do(context.Background(), sandboxJudge, s); res.Status != judge.StatusAccepted {
+		t.Fatalf("status = %d, msg = %s", res.Status, res.Msg)
+	}
+
+	// 创建socket不在系统调用白名单中
+	s.Path = writeCode(t, `package main
+
+import (
+	"fmt"
+	"net"
+)
+
+func main() {
+	_, err := net.Dial("tcp", "127.0.0.1:3306")
+	fmt.Println(err)
+}
+`)
+	if res := judge.Do(context.Background(), sandboxJudge, s); res.Status != judge.StatusRestrictedSyscall {
+		t.Fatalf("status = %d, msg = %s", res.Status, res.Msg)
+	}
+}
+
+func TestLocalJudgeProcLimit(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("sandbox is only supported on linux")
+	}
+	// 隔离环境中不限制进程数时拒绝运行
+	if err := (&judge.LocalJudge{Sandbox: true}).Validate(); err == nil {
+		t.Fatal("sandbox without process limit should be rejected")
+	}
+	// 每个锁定的协程占用一个线程，线程与进程一样计入限制
+	s := &judge.Submission{
+		Path: writeCode(t, `package main
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+)
+
+func main() {
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			runtime.LockOSThread()
+			time.Sleep(time.Millisecond * 200)
+		}()
+	}
+	wg.Wait()
+	fmt.Println("ok")
+}
+`),
+		Limit:     judge.Limit{MaxRuntime: 1000, MaxMem: 64 * 1024},
+		TestCases: []*judge.TestCase{{Identity: "1", Output: "ok\n"}},
+	}
+	j := &judge.LocalJudge{InitPath: sandboxJudge.(*judge.LocalJudge).InitPath, Sandbox: true}
+	if res := judge.Do(context.Background(), j, s); res.Status != judge.StatusSystemError {
+		t.Fatalf("status = %d, msg = %s", res.Status, res.Msg)
+	}
+	if res := judge.Do(context.Background(), sandboxJudge, s); res.Status != judge.StatusAccepted {
+		t.Fatalf("status = %d, msg = %s", res.Status, res.Msg)
+	}
+	j.MaxProcs = 32
+	if res := judge.Do(context.Background(), j, s); res.Status != judge.StatusRuntimeError {
+		t.Fatalf("status = %d, msg = %s", res.Status, res.Msg)
+	}
+}
+
+func TestLocalJudgeLanguages(t *testing.T) {
+	codes := map[string]string{
+		"c":      "#include <stdio.h>\nint main() {\n\tint a, b;\n\tscanf(\"%d %d\", &a, &b);\n\tprintf(\"%d\\n\", a + b);\n\treturn 0;\n}\n",
+		"cpp":    "#include <iostream>\nint main() {\n\tint a, b;\n\tstd::cin >> a >> b;\n\tstd::cout << a + b << std::endl;\n}\n",
+		"python": "a, b = map(int, input().split())\nprint(a + b)\n",
+		"java":   "import java.util.Scanner;\n\npublic class Main {\n\tpublic static void main(String[] args) {\n\t\tScanner in = new Scanner(System.in);\n\t\tSystem.out.println(in.nextInt() + in.nextInt());\n\t}\n}\n",
+	}
+	for name, code := range codes {
+		lang, ok := judge.GetLanguage(name)
+		if !ok {
+			t.Fatalf("language %s not found", name)
+		}
+		if _, err := exec.LookPath(lang.Compile[0]); err != nil {
+			t.Logf("skip %s: %v", name, err)
+			continue
+		}
+		s := &judge.Submission{
+			Path:      writeSource(t, lang.SourceFile, code),
+			Language:  name,
+			Limit:     judge.Limit{MaxRuntime: 2000, MaxMem: 256 * 1024},
+			TestCases: []*judge.TestCase{{Identity: "1", Input: "23 11\n", Output: "34\n"}},
+		}
+		if res := judge.Do(context.Background(), sandboxJudge, s); res.Status != judge.StatusAccepted {
+			t.Fatalf("%s: status = %d, msg = %s", name, res.Status, res.Msg)
+		}
+	}
+}
+
+func TestLocalJudgeJavaMemoryLimit(t *testing.T) {
+	lang, _ := judge.GetLanguage("java")
+	if _, err := exec.LookPath(lang.Compile[0]); err != nil {
+		t.Skip("skip java:", err)
+	}
+	// 没有配置cgroup时由-Xmx限制堆内存，超过时判断为超内存
+	s := &judge.Submission{
+		Path: writeSource(t, lang.SourceFile, `import java.util.ArrayList;
+import java.util.List;
+
+public class Main {
+	public static void main(String[] args) {
+		List<byte[]> list = new ArrayList<>();
+		for (int i = 0; i < 1024; i++) {
+			list.add(new byte[1 << 20]);
+		}
+		System.out.println(list.size());
+	}
+}
+`),
+		Language:  "java",
+		Limit:     judge.Limit{MaxRuntime: 5000, MaxMem: 64 * 1024},
+		TestCases: []*judge.TestCase{{Identity: "1", Output: "1024\n"}},
+	}
+	if res := judge.Do(context.Background(), sandboxJudge, s); res.Status != judge.StatusMemoryLimit {
+		t.Fatalf("status = %d, msg = %s", res.Status, res.Msg)
+	}
+}
+
+func TestEffectiveLimit(t *testing.T) {
+	base := judge.Limit{MaxRuntime: 1000, MaxMem: 65536}
+	python, _ := judge.GetLanguage("python")
+	golang, _ := judge.GetLanguage("go")
+	cases := []struct {
+		name     string
+		lang     *judge.Language
+		rules    []*judge.LimitRule
+		expected judge.Limit
+	}{
+		{"python default", python, nil, judge.Limit{MaxRuntime: 3000, MaxMem: 131072}},
+		{"go default", golang, []*judge.LimitRule{nil, nil}, base},
+		{"global factor, problem mem", python, []*judge.LimitRule{{TimeFactor: 5}, {MaxMem: 1024}}, judge.Limit{MaxRuntime: 5000, MaxMem: 1024}},
+		{"global absolute, problem factor", golang, []*judge.LimitRule{{MaxRuntime: 2000}, {TimeFactor: 10}}, judge.Limit{MaxRuntime: 10000, MaxMem: 65536}},
+		{"global factor, problem absolute", python, []*judge.LimitRule{{TimeFactor: 10, MemFactor: 4}, {MaxRuntime: 1500, MaxMem: 32768}}, judge.Limit{MaxRuntime: 1500, MaxMem: 32768}},
+		{"global absolute mem, problem mem factor", golang, []*judge.LimitRule{{MaxMem: 1024}, {MemFactor: 2}}, judge.Limit{MaxRuntime: 1000, MaxMem: 131072}},
+		{"problem time only keeps global mem", python, []*judge.LimitRule{{MaxRuntime: 2000, MaxMem: 1024}, {TimeFactor: 2}}, judge.Limit{MaxRuntime: 2000, MaxMem: 1024}},
+	}
+	for _, c := range cases {
+		if l := judge.EffectiveLimit(base, c.lang, c.rules...); l != c.expected {
+			t.Errorf("%s: limit = %+v, want %+v", c.name, l, c.expected)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	cases := []struct {
+		cmp      judge.Compare
+		expected string
+		actual   string
+		equal    bool
+	}{
+		{judge.Compare{}, "1 2\n", "1 2", false},
+		{judge.Compare{Mode: judge.CompareExact}, "1 2\n", "1 2\n", true},
+		{judge.Compare{Mode: judge.CompareTrailing}, "1 2\n3\n", "1 2  \r\n3\n\n", true},
+		{judge.Compare{Mode: judge.CompareTrailing}, "1 2\n", "1  2\n", false},
+		{judge.Compare{Mode: judge.CompareTrailing}, "1\n2\n\n\n", "1\n2", true},
+		{judge.Compare{Mode: judge.CompareTrailing}, "1\n2\n", "1\n", false},
+		{judge.Compare{Mode: judge.CompareExact}, "1 2\n", "1 2\n3", false},
+		{judge.Compare{Mode: judge.CompareToken}, "1 2", "1 2 3", false},
+		{judge.Compare{Mode: judge.CompareToken}, "1 2 3", "1 2", false},
+		{judge.Compare{Mode: judge.CompareToken}, "1 2\n3", "1\n2 3 ", true},
+		{judge.Compare{Mode: judge.CompareToken}, "yes", "YES", false},
+		{judge.Compare{Mode: judge.CompareNoCase}, "yes", "YES\n", true},
+		{judge.Compare{Mode: judge.CompareFloat}, "0.3333333", "0.33333334 ", true},
+		{judge.Compare{Mode: judge.CompareFloat}, "0.333", "0.334", false},
+		{judge.Compare{Mode: judge.CompareFloat, AbsEpsilon: 1e-2}, "0.333", "0.334", true},
+		{judge.Compare{Mode: judge.CompareFloat, RelEpsilon: 1e-3}, "100000", "100050", true},
+		{judge.Compare{Mode: judge.CompareFloat}, "nan", "nan", true},
+		{judge.Compare{Mode: judge.CompareFloat}, "1 a", "1 b", false},
+	}
+	for _, c := range cases {
+		if got := c.cmp.Equal(c.expected, c.actual); got != c.equal {
+			t.Errorf("%+v Equal(%q, %q) = %v", c.cmp, c.expected, c.actual, got)
+		}
+	}
+}
+
+func TestLocalJudgeCaseFiles(t *testing.T) {
+	// 测试数据在文件中，输入直接作为用户程序的标准输入
+	dir := t.TempDir()
+	input := filepath.Join(dir, "1.in")
+	output := filepath.Join(dir, "1.out")
+	var in, out strings.Builder
+	for i := 0; i < 100000; i++ {
+		fmt.Fprintf(&in, "%d %d\n", i, i)
+		fmt.Fprintf(&out, "%d\n", 2*i)
+	}
+	if err := os.WriteFile(input, []byte(in.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(output, []byte(out.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s := &judge.Submission{
+		Path:      writeCode(t, "package main\n\nimport (\n\t\"bufio\"\n\t\"fmt\"\n\t\"os\"\n)\n\nfunc main() {\n\tr := bufio.NewReader(os.Stdin)\n\tw := bufio.NewWriter(os.Stdout)\n\tdefer w.Flush()\n\tvar a, b int\n\tfor {\n\t\tif _, err := fmt.Fscan(r, &a, &b); err != nil {\n\t\t\treturn\n\t\t}\n\t\tfmt.Fprintln(w, a+b)\n\t}\n}\n"),
+		Limit:     judge.Limit{MaxRuntime: 2000, MaxMem: 256 * 1024},
+		TestCases: []*judge.TestCase{{Identity: "1", InputFile: input, OutputFile: output}},
+	}
+	if res := judge.Do(context.Background(), sandboxJudge, s); res.Status != judge.StatusAccepted {
+		t.Fatalf("status = %d, msg = %s", res.Status, res.Msg)
+	}
+	s.TestCases[0].OutputFile = input
+	if res := judge.Do(context.Background(), sandboxJudge, s); res.Status != judge.StatusWrongAnswer {
+		t.Fatalf("status = %d, msg = %s", res.Status, res.Msg)
+	}
+}
+
+func TestLocalJudgeChecker(t *testing.T) {
+	python, _ := judge.GetLanguage("python")
+	if _, err := exec.LookPath(python.Run[0]); err != nil {
+		t.Skip(err)
+	}
+	// 输出任意两个和为输入的非负整数
+	checker := writeSource(t, python.SourceFile, `import sys
+n = int(open(sys.argv[1]).read())
+a, b = map(int, open(sys.argv[3]).read().split())
+if a < 0 or b < 0 or a + b != n:
+    print("sum is", a + b)
+    sys.exit(1)
+`)
+	s := &judge.Submission{
+		Path:  writeCode(t, "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tvar n int\n\tfmt.Scanln(&n)\n\tfmt.Println(1, n-1)\n}\n"),
+		Limit: judge.Limit{MaxRuntime: 1000, MaxMem: 64 * 1024},
+		Compare: judge.Compare{
+			Checker: &judge.Program{Dir: filepath.Dir(checker), Language: python},
+		},
+		TestCases: []*judge.TestCase{{Identity: "1", Input: "10\n", Output: "5 5\n"}},
+	}
+	if res := judge.Do(context.Background(), judge.Default, s); res.Status != judge.StatusAccepted {
+		t.Fatalf("status = %d, msg = %s", res.Status, res.Msg)
+	}
+	s.Path = writeCode(t, "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(1, 1)\n}\n")
+	res := judge.Do(context.Background(), judge.Default, s)
+	if res.Status != judge.StatusWrongAnswer || !strings.Contains(res.Msg, "sum is 2") {
+		t.Fatalf("status = %d, msg = %s", res.Status, res.Msg)
+	}
+}
+
+func TestLocalJudgeInteractor(t *testing.T) {
+	python, _ := judge.GetLanguage("python")
+	if _, err := exec.LookPath(python.Run[0]); err != nil {
+		t.Skip(err)
+	}
+	// 猜数：用户程序输出猜测的数，交互程序回答<、>或=
+	interactor := writeSource(t, python.SourceFile, `import sys
+n = int(open(sys.argv[1]).read())
+for i in range(20):
+    line = sys.stdin.readline()
+    if not line:
+        break
+    g = int(line)
+    if g == n:
+        print("=", flush=True)
+        sys.exit(0)
+    print("<" if n < g else ">", flush=True)
+sys.stderr.write("too many guesses")
+sys.exit(1)
+`)
+	s := &judge.Submission{
+		Path: writeCode(t, `package main
+
+import "fmt"
+
+func main() {
+	lo, hi := 1, 1000
+	for lo <= hi {
+		mid := (lo + hi) / 2
+		fmt.Println(mid)
+		var r string
+		fmt.Scanln(&r)
+		switch r {
+		case "=":
+			return
+		case "<":
+			hi = mid - 1
+		default:
+			lo = mid + 1
+		}
+	}
+}
+`),
+		Limit: judge.Limit{MaxRuntime: 1000, MaxMem: 64 * 1024},
+		Compare: judge.Compare{
+			Interactor: &judge.Program{Dir: filepath.Dir(interactor), Language: python},
+		},
+		TestCases: []*judge.TestCase{
+			{Identity: "1", Input: "1\n"},
+			{Identity: "2", Input: "777\n"},
+		},
+	}
+	j := judge.Default
+	if runtime.GOOS == "linux" {
+		j = sandboxJudge
+	}
+	if res := judge.Do(context.Background(), j, s); res.Status != judge.StatusAccepted {
+		t.Fatalf("status = %d, msg = %s", res.Status, res.Msg)
+	}
+	// 一直猜同一个数
+	s.Path = writeCode(t, "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfor {\n\t\tfmt.Println(500)\n\t\tvar r string\n\t\tif _, err := fmt.Scanln(&r); err != nil {\n\t\t\treturn\n\t\t}\n\t}\n}\n")
+	res := judge.Do(context.Background(), j, s)
+	if res.Status != judge.StatusWrongAnswer || !strings.Contains(res.Msg, "too many guesses") {
+		t.Fatalf("status = %d, msg = %s", res.Status, res.Msg)
+	}
+}
+
+func TestLocalJudgeInteractorTimeout(t *testing.T) {
+	python, _ := judge.GetLanguage("python")
+	if _, err := exec.LookPath(python.Run[0]); err != nil {
+		t.Skip(err)
+	}
+	// 用户程序退出后交互程序不结束，创建的子进程也持有标准错误
+	interactor := writeSource(t, python.SourceFile, `import subprocess, time
+subprocess.Popen(["sleep", "60"])
+time.sleep(60)
+`)
+	s := &judge.Submission{
+		Path:  writeCode(t, "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(1)\n}\n"),
+		Limit: judge.Limit{MaxRuntime: 500, MaxMem: 64 * 1024},
+		Compare: judge.Compare{
+			Interactor: &judge.Program{Dir: filepath.Dir(interactor), Language: python},
+		},
+		TestCases: []*judge.TestCase{{Identity: "1", Input: "1\n"}},
+	}
+	start := time.Now()
+	res := judge.Do(context.Background(), judge.Default, s)
+	if res.Status != judge.StatusTimeLimit {
+		t.Fatalf("status = %d, msg = %s", res.Status, res.Msg)
+	}
+	if d := time.Since(start); d > time.Second*10 {
+		t.Fatalf("interactor was not killed, took %v", d)
+	}
+}
+
+func TestLocalJudgeStderr(t *testing.T) {
+	s := &judge.Submission{
+		Path:      writeCode(t, "package main\n\nimport (\n\t\"fmt\"\n\t\"os\"\n\t\"strings\"\n)\n\nfunc main() {\n\tfmt.Fprint(os.Stderr, \"debug \"+strings.Repeat(\"x\", 4096))\n\tfmt.Println(1)\n}\n"),
+		Limit:     judge.Limit{MaxRuntime: 1000, MaxMem: 64 * 1024},
+		TestCases: []*judge.TestCase{{Identity: "1", Output: "1\n"}},
+	}
+	res := judge.Do(context.Background(), judge.Default, s)
+	if res.Status != judge.StatusAccepted {
+		t.Fatalf("status = %d, msg = %s", res.Status, res.Msg)
+	}
+	if stderr := res.Cases[0].Stderr; !strings.HasPrefix(stderr, "debug ") || len(stderr) > 1024 {
+		t.Fatalf("stderr = %q", stderr)
+	}
+}
+
+func TestLocalJudgeRuntimeError(t *testing.T) {
+	codes := map[string]string{
+		"exit status": "package main\n\nimport \"os\"\n\nfunc main() {\n\tos.Exit(3)\n}\n",
+		"panic":       "package main\n\nfunc main() {\n\tvar m map[string]int\n\tm[\"a\"] = 1\n}\n",
+	}
+	if runtime.GOOS != "windows" {
+		codes["signal"] = "package main\n\nimport \"syscall\"\n\nfunc main() {\n\tsyscall.Kill(syscall.Getpid(), syscall.SIGKILL)\n}\n"
+	}
+	want := map[string]struct {
+		status int
+		msg    string
+	}{
+		"exit status": {judge.StatusRuntimeError, "退出码 3"},
+		"panic":       {judge.StatusRuntimeError, "退出码 2"},
+		"signal":      {judge.StatusRuntimeError, "killed"},
+	}
+	for name, code := range codes {
+		s := &judge.Submission{
+			Path:      writeCode(t, code),
+			Limit:     judge.Limit{MaxRuntime: 5000, MaxMem: 256 * 1024},
+			TestCases: []*judge.TestCase{{Identity: "1", Output: "1\n"}},
+		}
+		res := judge.Do(context.Background(), judge.Default, s)
+		if res.Status != want[name].status || !strings.Contains(res.Msg, want[name].msg) {
+			t.Errorf("%s: status = %d, msg = %s", name, res.Status, res.Msg)
+		}
+	}
+}
+
+func TestLocalJudgeStopOnFailure(t *testing.T) {
+	s := &judge.Submission{
+		Path:  writeCode(t, "package main\n\nimport (\n\t\"fmt\"\n\t\"time\"\n)\n\nfunc main() {\n\tvar n int\n\tfmt.Scanln(&n)\n\ttime.Sleep(time.Duration(n) * time.Second)\n\tfmt.Println(n)\n}\n"),
+		Limit: judge.Limit{MaxRuntime: 3000, MaxMem: 64 * 1024},
+		TestCases: []*judge.TestCase{
+			{Identity: "1", Input: "0\n", Output: "1\n"},
+			{Identity: "2", Input: "5\n", Output: "5\n"},
+		},
+		StopOnFailure: true,
+	}
+	start := time.Now()
+	res := judge.Do(context.Background(), judge.Default, s)
+	if res.Status != judge.StatusWrongAnswer || res.Cases[1].Status != judge.StatusSkipped {
+		t.Fatalf("status = %d, msg = %s, case 2 status = %d %+v", res.Status, res.Msg, res.Cases[1].Status, *res.Cases[0])
+	}
+	if d := time.Since(start); d > 3*time.Second {
+		t.Fatalf("judge took %v, want the sleeping case to be stopped", d)
+	}
+}
+
+func TestLocalJudgeKillChildren(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("process groups are only used on linux")
+	}
+	// 子进程继承标准输出并一直运行
+	s := &judge.Submission{
+		Path:      writeCode(t, "package main\n\nimport (\n\t\"fmt\"\n\t\"os\"\n\t\"os/exec\"\n)\n\nfunc main() {\n\tcmd := exec.Command(\"sleep\", \"30\")\n\tcmd.Stdout = os.Stdout\n\tcmd.Start()\n\tfmt.Println(1)\n}\n"),
+		Limit:     judge.Limit{MaxRuntime: 500, MaxMem: 64 * 1024},
+		TestCases: []*judge.TestCase{{Identity: "1", Output: "1\n"}},
+	}
+	start := time.Now()
+	res := judge.Do(context.Background(), judge.Default, s)
+	if d := time.Since(start); d > 10*time.Second {
+		t.Fatalf("judge took %v, status = %d", d, res.Status)
+	}
+	if out, err := exec.Command("pgrep", "-f", "^sleep 30$").Output(); err == nil {
+		t.Fatalf("child process still running: %s", out)
+	}
+}
+
+func TestLocalJudgeOutputLimit(t *testing.T) {
+	// 一直输出1
+	s := &judge.Submission{
+		Path:      writeCode(t, "package main\n\nimport (\n\t\"bufio\"\n\t\"os\"\n)\n\nfunc main() {\n\tw := bufio.NewWriter(os.Stdout)\n\tfor {\n\t\tw.WriteString(\"1\\n\")\n\t}\n}\n"),
+		Limit:     judge.Limit{MaxRuntime: 5000, MaxMem: 64 * 1024, MaxOutput: 64},
+		TestCases: []*judge.TestCase{{Identity: "1", Output: strings.Repeat("1\n", 64*1024)}},
+	}
+	res := judge.Do(context.Background(), judge.Default, s)
+	if res.Status != judge.StatusOutputLimit {
+		t.Fatalf("status = %d, msg = %s", res.Status, res.Msg)
+	}
+	// 输出与标准输出不一致时提前结束
+	for _, mode := range []string{judge.CompareExact, judge.CompareTrailing, judge.CompareToken} {
+		s.Limit.MaxOutput = 0
+		s.Compare = judge.Compare{Mode: mode}
+		s.TestCases[0].Output = "2\n"
+		res = judge.Do(context.Background(), judge.Default, s)
+		if res.Status != judge.StatusWrongAnswer || res.Time > 1000 {
+			t.Fatalf("%s: status = %d, msg = %s, time = %d", mode, res.Status, res.Msg, res.Time)
+		}
+	}
+}
+
+func TestLocalJudgeKilledAfterSleep(t *testing.T) {
+	// 等待超过时间限制的墙上时间后输出，被结束时不应按照墙上时间判断为超时
+	code := "package main\n\nimport (\n\t\"fmt\"\n\t\"strings\"\n\t\"time\"\n)\n\nfunc main() {\n\ttime.Sleep(time.Millisecond * 1200)\n\tfmt.Print(strings.Repeat(\"2\\n\", %d))\n\ttime.Sleep(time.Second * 10)\n}\n"
+	cases := map[string]struct {
+		lines    int
+		expected string
+		status   int
+	}{
+		"mismatch":     {1, "1\n", judge.StatusWrongAnswer},
+		"output limit": {64 * 1024, strings.Repeat("2\n", 64*1024), judge.StatusOutputLimit},
+	}
+	for name, c := range cases {
+		s := &judge.Submission{
+			Path:      writeCode(t, fmt.Sprintf(code, c.lines)),
+			Limit:     judge.Limit{MaxRuntime: 1000, MaxMem: 64 * 1024, MaxOutput: 64},
+			TestCases: []*judge.TestCase{{Identity: "1", Output: c.expected}},
+		}
+		res := judge.Do(context.Background(), judge.Default, s)
+		if res.Status != c.status || res.Time >= 1000 {
+			t.Fatalf("%s: status = %d, msg = %s, time = %d", name, res.Status, res.Msg, res.Time)
+		}
+	}
+}
+
+func TestJudgeExecute(t *testing.T) {
+	python, _ := judge.GetLanguage("python")
+	if _, err := exec.LookPath(python.Run[0]); err != nil {
+		t.Skip(err)
+	}
+	limit := judge.Limit{MaxRuntime: 1000, MaxMem: 256 * 1024, MaxOutput: 1}
+	path := writeSource(t, python.SourceFile, "import sys\na, b = map(int, input().split())\nprint(a + b)\nprint('debug', file=sys.stderr)\n")
+	res := judge.Execute(context.Background(), sandboxJudge, path, "python", "1 2\n", limit)
+	if res.Status != judge.StatusAccepted || res.Stdout != "3\n" || res.Stderr != "debug\n" {
+		t.Fatalf("res = %+v", res)
+	}
+	res = judge.Execute(context.Background(), sandboxJudge, path, "python", "1\n", limit)
+	if res.Status != judge.StatusRuntimeError || !strings.Contains(res.Stderr, "ValueError") {
+		t.Fatalf("res = %+v", res)
+	}
+	path = writeSource(t, python.SourceFile, "while True:\n    print('x' * 100)\n")
+	res = judge.Execute(context.Background(), sandboxJudge, path, "python", "", limit)
+	if res.Status != judge.StatusOutputLimit || len(res.Stdout) > 1024 {
+		t.Fatalf("status = %d, msg = %s, stdout = %d", res.Status, res.Msg, len(res.Stdout))
+	}
+	res = judge.Execute(context.Background(), sandboxJudge, writeCode(t, "package main\n\nfunc main() {\n"), "go", "", limit)
+	if res.Status != judge.StatusCompileError {
+		t.Fatalf("res = %+v", res)
+	}
+}
@@ -0,0 +1,133 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"gin_gorm_oj/helper"
+	"gin_gorm_oj/judge"
+	"gin_gorm_oj/models"
+	"gin_gorm_oj/storage"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// deleteProblem 删除测试中创建的问题、测试用例和语言限制规则
+func deleteProblem(t *testing.T, identity string) {
+	deleteRows(t, new(models.TestCase), "problem_identity = ?", identity)
+	deleteRows(t, new(models.LanguageLimit), "problem_identity = ?", identity)
+	deleteRows(t, new(models.ProblemBasic), "identity = ?", identity)
+}
+
+// readTestCase 读取测试用例的输入和标准输出
+func readTestCase(t *testing.T, tc *models.TestCase) (string, string) {
+	ctx := context.Background()
+	in, err := tc.OpenInput(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer in.Close()
+	out, err := tc.OpenOutput(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	input, _ := io.ReadAll(in)
+	output, _ := io.ReadAll(out)
+	return string(input), string(output)
+}
+
+func TestProblemExportImport(t *testing.T) {
+	requireDB(t, new(models.ProblemBasic), new(models.ProblemCategory), new(models.CategoryBasic), new(models.TestCase), new(models.LanguageLimit))
+	old := storage.Default
+	storage.Default = &storage.Local{Dir: t.TempDir()}
+	defer func() { storage.Default = old }()
+
+	pb := &models.ProblemBasic{
+		Identity:    helper.GetUUID(),
+		Title:       "A+B round trip",
+		Content:     "# A+B\n\n输出两个数的和",
+		MaxRuntime:  1500,
+		MaxMem:      65536,
+		MaxOutput:   128,
+		CompareMode: judge.CompareFloat,
+		AbsEpsilon:  1e-6,
+		RelEpsilon:  1e-4,
+	}
+	cases := [][2]string{{"1 2\n", "3\n"}, {"0.5 0.25\n", "0.75\n"}, {"-1 1\n", "0\n"}}
+	for i, c := range cases {
+		tc, err := models.NewTestCase(context.Background(), pb.Identity, helper.GetUUID(), c[0], c[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		tc.IsSample = i == 1
+		pb.TestCase = append(pb.TestCase, tc)
+	}
+	rule := &models.LanguageLimit{ProblemIdentity: pb.Identity, Language: "python", TimeFactor: 2, MaxMem: 131072}
+	if err := models.DB.Create(pb).Error; err != nil {
+		t.Fatal(err)
+	}
+	defer deleteProblem(t, pb.Identity)
+	if err := models.DB.Create(rule).Error; err != nil {
+		t.Fatal(err)
+	}
+	token := testToken(t, "test-admin", 1)
+
+	w := serve(httptest.NewRequest(http.MethodGet, "/admin/problem-export?identity="+pb.Identity, nil), token)
+	if w.Header().Get("Content-Type") != "application/zip" {
+		t.Fatalf("export: %s", w.Body.String())
+	}
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, err := mw.CreateFormFile("files", pb.Identity+".zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw.Write(w.Body.Bytes())
+	mw.Close()
+	res := callAPI(t, http.MethodPost, "/admin/problem-import", &body, mw.FormDataContentType(), token)
+	var imported []struct {
+		Identity string `json:"identity"`
+		Msg      string `json:"msg"`
+	}
+	decodeData(t, res, &imported)
+	if res.Code != 200 || len(imported) != 1 || imported[0].Identity == "" {
+		t.Fatalf("import: %s %s", res.Msg, res.Data)
+	}
+	identity := imported[0].Identity
+	defer deleteProblem(t, identity)
+
+	got := new(models.ProblemBasic)
+	err = models.DB.Where("identity = ?", identity).Preload("TestCase", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	}).First(got).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Title != pb.Title || got.Content != pb.Content || got.MaxRuntime != pb.MaxRuntime || got.MaxMem != pb.MaxMem || got.MaxOutput != pb.MaxOutput {
+		t.Fatalf("problem = %+v", got)
+	}
+	if got.Compare() != pb.Compare() {
+		t.Fatalf("compare = %+v, want %+v", got.Compare(), pb.Compare())
+	}
+	if len(got.TestCase) != len(cases) {
+		t.Fatalf("%d test cases, want %d", len(got.TestCase), len(cases))
+	}
+	for i, tc := range got.TestCase {
+		input, output := readTestCase(t, tc)
+		if input != cases[i][0] || output != cases[i][1] || tc.IsSample != (i == 1) {
+			t.Fatalf("test case %d: %q %q sample %v", i, input, output, tc.IsSample)
+		}
+	}
+	rules := make([]*models.LanguageLimit, 0)
+	if err := models.DB.Where("problem_identity = ?", identity).Find(&rules).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 1 || rules[0].Language != rule.Language || rules[0].TimeFactor != rule.TimeFactor || rules[0].MaxMem != rule.MaxMem || rules[0].MaxRuntime != 0 || rules[0].MemFactor != 0 {
+		t.Fatalf("language limits = %+v", rules)
+	}
+}
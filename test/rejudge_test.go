@@ -0,0 +1,150 @@
+package test
+
+import (
+	"gin_gorm_oj/define"
+	"gin_gorm_oj/dispatch"
+	"gin_gorm_oj/helper"
+	"gin_gorm_oj/judge"
+	"gin_gorm_oj/models"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// checkPassNum 检查用户和问题的通过个数和提交次数
+func checkPassNum(t *testing.T, user, problem string, pass, submit int64) {
+	t.Helper()
+	ub := new(models.UserBasic)
+	if err := models.DB.Where("identity = ?", user).First(ub).Error; err != nil {
+		t.Fatal(err)
+	}
+	pb := new(models.ProblemBasic)
+	if err := models.DB.Where("identity = ?", problem).First(pb).Error; err != nil {
+		t.Fatal(err)
+	}
+	if ub.PassNum != pass || ub.SubmitNum != submit || pb.PassNum != pass || pb.SubmitNum != submit {
+		t.Fatalf("user %d/%d, problem %d/%d, want %d/%d", ub.PassNum, ub.SubmitNum, pb.PassNum, pb.SubmitNum, pass, submit)
+	}
+}
+
+// setStatus 修改提交的判断状态后重新统计通过个数
+func setStatus(t *testing.T, sb *models.SubmitBasic, status int) {
+	t.Helper()
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(new(models.SubmitBasic)).Where("identity = ?", sb.Identity).Update("status", status).Error; err != nil {
+			return err
+		}
+		return models.UpdatePassNum(tx, []string{sb.UserIdentity}, []string{sb.ProblemIdentity})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// loadSubmit 重新读取提交
+func loadSubmit(t *testing.T, identity string) *models.SubmitBasic {
+	t.Helper()
+	sb := new(models.SubmitBasic)
+	if err := models.DB.Where("identity = ?", identity).First(sb).Error; err != nil {
+		t.Fatal(err)
+	}
+	return sb
+}
+
+// popJob 取出重新判断放入队列的任务
+func popJob(t *testing.T, identity string) {
+	t.Helper()
+	job, err := dispatch.Queue.Pop(ctx, "rejudge-test-worker", []string{judge.DefaultLanguage}, time.Second*3)
+	if err != nil || job == nil || job.Identity != identity {
+		t.Fatal(job, err)
+	}
+	if err := dispatch.Queue.Ack(ctx, job); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRejudgePassNum(t *testing.T) {
+	requireDB(t, new(models.UserBasic), new(models.ProblemBasic), new(models.SubmitBasic),
+		new(models.SubmitCaseResult), new(models.TestCase), new(models.LanguageLimit))
+	ub := &models.UserBasic{Identity: helper.GetUUID(), SubmitNum: 1}
+	pb := &models.ProblemBasic{Identity: helper.GetUUID(), MaxRuntime: 1000, MaxMem: 64, SubmitNum: 1}
+	tc := &models.TestCase{Identity: helper.GetUUID(), ProblemIdentity: pb.Identity, Input: "1\n", Output: "1\n"}
+	path := filepath.Join(t.TempDir(), "main.go")
+	if err := os.WriteFile(path, []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sb := &models.SubmitBasic{
+		Identity:        helper.GetUUID(),
+		ProblemIdentity: pb.Identity,
+		UserIdentity:    ub.Identity,
+		Path:            path,
+		Language:        judge.DefaultLanguage,
+		Status:          judge.StatusAccepted,
+		CaseResults:     []*models.SubmitCaseResult{{TestCaseIdentity: tc.Identity, Status: judge.StatusAccepted}},
+	}
+	for _, v := range []interface{}{ub, pb, tc, sb} {
+		if err := models.DB.Create(v).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer deleteRows(t, new(models.UserBasic), "identity = ?", ub.Identity)
+	defer deleteRows(t, new(models.ProblemBasic), "identity = ?", pb.Identity)
+	defer deleteRows(t, new(models.TestCase), "problem_identity = ?", pb.Identity)
+	defer deleteRows(t, new(models.SubmitCaseResult), "submit_identity = ?", sb.Identity)
+	defer deleteRows(t, new(models.SubmitBasic), "identity = ?", sb.Identity)
+
+	// 通过 -> 未通过 -> 通过，提交次数不变
+	setStatus(t, sb, judge.StatusAccepted)
+	checkPassNum(t, ub.Identity, pb.Identity, 1, 1)
+	setStatus(t, sb, judge.StatusWrongAnswer)
+	checkPassNum(t, ub.Identity, pb.Identity, 0, 1)
+	setStatus(t, sb, judge.StatusAccepted)
+	checkPassNum(t, ub.Identity, pb.Identity, 1, 1)
+
+	// 重新判断后提交为待判断状态，测试用例结果被删除，通过个数重新统计
+	cnt, err := dispatch.Rejudge(ctx, []*models.SubmitBasic{loadSubmit(t, sb.Identity)}, false)
+	if err != nil || cnt != 1 {
+		t.Fatal(cnt, err)
+	}
+	popJob(t, sb.Identity)
+	if got := loadSubmit(t, sb.Identity); got.Status != judge.StatusPending {
+		t.Fatal("status =", got.Status)
+	}
+	var results int64
+	models.DB.Model(new(models.SubmitCaseResult)).Where("submit_identity = ?", sb.Identity).Count(&results)
+	if results != 0 {
+		t.Fatal("case results =", results)
+	}
+	checkPassNum(t, ub.Identity, pb.Identity, 0, 1)
+
+	// 正在判断的提交不会重新判断，force只对待判断超时的提交生效
+	for _, force := range []bool{false, true} {
+		cnt, err = dispatch.Rejudge(ctx, []*models.SubmitBasic{loadSubmit(t, sb.Identity)}, force)
+		if err != nil || cnt != 0 {
+			t.Fatal(force, cnt, err)
+		}
+	}
+	stale := time.Now().Add(-time.Duration(define.RejudgePendingTimeout+60) * time.Second)
+	if err := models.DB.Model(new(models.SubmitBasic)).Where("identity = ?", sb.Identity).UpdateColumn("updated_at", stale).Error; err != nil {
+		t.Fatal(err)
+	}
+	cnt, err = dispatch.Rejudge(ctx, []*models.SubmitBasic{loadSubmit(t, sb.Identity)}, false)
+	if err != nil || cnt != 0 {
+		t.Fatal(cnt, err)
+	}
+	cnt, err = dispatch.Rejudge(ctx, []*models.SubmitBasic{loadSubmit(t, sb.Identity)}, true)
+	if err != nil || cnt != 1 {
+		t.Fatal(cnt, err)
+	}
+	popJob(t, sb.Identity)
+	if got := loadSubmit(t, sb.Identity); got.Status != judge.StatusPending || !got.UpdatedAt.After(stale) {
+		t.Fatal(got.Status, got.UpdatedAt)
+	}
+
+	// 重新判断的结果为通过时通过个数恢复
+	setStatus(t, sb, judge.StatusAccepted)
+	checkPassNum(t, ub.Identity, pb.Identity, 1, 1)
+}
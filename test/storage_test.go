@@ -0,0 +1,115 @@
+package test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"gin_gorm_oj/storage"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// testStorage 保存、读取和删除一个对象
+func testStorage(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	data := "1 2\n"
+	if err := s.Put(ctx, "testcase/p/1.in", strings.NewReader(data), int64(len(data))); err != nil {
+		t.Fatal(err)
+	}
+	r, err := s.Get(ctx, "testcase/p/1.in")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := io.ReadAll(r)
+	r.Close()
+	if string(got) != data {
+		t.Fatalf("get = %q", got)
+	}
+	if err := s.Delete(ctx, "testcase/p/1.in"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get(ctx, "testcase/p/1.in"); !errors.Is(err, storage.ErrNotExist) {
+		t.Fatalf("get after delete err = %v", err)
+	}
+	if err := s.Delete(ctx, "testcase/p/1.in"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLocalStorage(t *testing.T) {
+	s := &storage.Local{Dir: t.TempDir()}
+	testStorage(t, s)
+	if err := s.Put(context.Background(), "../1.in", strings.NewReader(""), 0); err != nil {
+		t.Fatal(err)
+	}
+	// key不能跳出存储目录
+	if _, err := os.Stat(s.Dir + "/1.in"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestS3Storage(t *testing.T) {
+	// 模拟S3的路径形式接口，只检查请求是否签名
+	var mu sync.Mutex
+	objects := make(map[string][]byte)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=minio/") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Method {
+		case http.MethodPut:
+			objects[r.URL.Path], _ = io.ReadAll(r.Body)
+		case http.MethodGet:
+			data, ok := objects[r.URL.Path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write(data)
+		case http.MethodDelete:
+			delete(objects, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+	s := &storage.S3{Endpoint: server.URL, Region: "us-east-1", Bucket: "oj", AccessKey: "minio", SecretKey: "secret"}
+	testStorage(t, s)
+}
+
+func TestStorageCache(t *testing.T) {
+	ctx := context.Background()
+	s := &storage.Local{Dir: t.TempDir()}
+	data := "1 2\n"
+	if err := s.Put(ctx, "1.in", strings.NewReader(data), int64(len(data))); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256([]byte(data))
+	c := &storage.Cache{Storage: s, Dir: t.TempDir()}
+	p, err := c.File(ctx, "1.in", hex.EncodeToString(sum[:]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(p); string(got) != data {
+		t.Fatalf("cached = %q", got)
+	}
+	// 已经缓存的对象不再读取存储
+	s.Delete(ctx, "1.in")
+	if _, err := c.File(ctx, "1.in", hex.EncodeToString(sum[:])); err != nil {
+		t.Fatal(err)
+	}
+	// 校验和不一致
+	s.Put(ctx, "2.in", strings.NewReader(data), int64(len(data)))
+	wrong := sha256.Sum256([]byte("other"))
+	if _, err := c.File(ctx, "2.in", hex.EncodeToString(wrong[:])); err == nil {
+		t.Fatal("checksum mismatch not detected")
+	}
+}
@@ -0,0 +1,52 @@
+package test
+
+import (
+	"gin_gorm_oj/helper"
+	"gin_gorm_oj/models"
+	"net/http"
+	"testing"
+)
+
+func TestSubmitDetailStderr(t *testing.T) {
+	requireDB(t, new(models.SubmitBasic), new(models.SubmitCaseResult), new(models.TestCase))
+	problem, user := helper.GetUUID(), helper.GetUUID()
+	sample := &models.TestCase{Identity: helper.GetUUID(), ProblemIdentity: problem, IsSample: true}
+	hidden := &models.TestCase{Identity: helper.GetUUID(), ProblemIdentity: problem}
+	sb := &models.SubmitBasic{
+		Identity:        helper.GetUUID(),
+		ProblemIdentity: problem,
+		UserIdentity:    user,
+		Status:          2,
+		CaseResults: []*models.SubmitCaseResult{
+			{TestCaseIdentity: sample.Identity, Status: 1, Stderr: "sample stderr"},
+			{TestCaseIdentity: hidden.Identity, Status: 2, Stderr: "hidden stderr"},
+		},
+	}
+	if err := models.DB.Create([]*models.TestCase{sample, hidden}).Error; err != nil {
+		t.Fatal(err)
+	}
+	defer deleteRows(t, new(models.TestCase), "problem_identity = ?", problem)
+	if err := models.DB.Create(sb).Error; err != nil {
+		t.Fatal(err)
+	}
+	defer deleteRows(t, new(models.SubmitCaseResult), "submit_identity = ?", sb.Identity)
+	defer deleteRows(t, new(models.SubmitBasic), "identity = ?", sb.Identity)
+
+	tokens := map[string]string{
+		"anonymous":  "",
+		"other user": testToken(t, helper.GetUUID(), 0),
+		"owner":      testToken(t, user, 0),
+	}
+	for name, token := range tokens {
+		res := callAPI(t, http.MethodGet, "/submit-detail?identity="+sb.Identity, nil, "", token)
+		data := new(models.SubmitBasic)
+		decodeData(t, res, data)
+		if res.Code != 200 || len(data.CaseResults) != 2 {
+			t.Fatalf("%s: %s %s", name, res.Msg, res.Data)
+		}
+		// 隐藏测试用例的标准错误对提交者同样不返回
+		if data.CaseResults[0].Stderr != "sample stderr" || data.CaseResults[1].Stderr != "" {
+			t.Fatalf("%s: stderr = %q, %q", name, data.CaseResults[0].Stderr, data.CaseResults[1].Stderr)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package test
+
+import (
+	"context"
+	"gin_gorm_oj/judge"
+	"gin_gorm_oj/queue"
+	"gin_gorm_oj/worker"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// countJudge 记录编译次数
+type countJudge struct {
+	fakeJudge
+	compiled int
+}
+
+func (j *countJudge) Compile(ctx context.Context, lang *judge.Language, path string) (*judge.Program, error) {
+	j.compiled++
+	return j.fakeJudge.Compile(ctx, lang, path)
+}
+
+func TestProgramCache(t *testing.T) {
+	j := new(countJudge)
+	c := &worker.ProgramCache{Judge: j, Dir: t.TempDir()}
+	p, err := c.Get(context.Background(), nil)
+	if p != nil || err != nil {
+		t.Fatal(p, err)
+	}
+	checker := &queue.Program{Language: "python", Code: "print(1)\n"}
+	p1, err := c.Get(context.Background(), checker)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p2, err := c.Get(context.Background(), checker)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p1.Dir != p2.Dir || j.compiled != 1 {
+		t.Fatal(p1.Dir, p2.Dir, j.compiled)
+	}
+	code, err := os.ReadFile(filepath.Join(p1.Dir, "main.py"))
+	if err != nil || string(code) != checker.Code {
+		t.Fatal(string(code), err)
+	}
+	p3, err := c.Get(context.Background(), &queue.Program{Language: "python", Code: "print(2)\n"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p3.Dir == p1.Dir || j.compiled != 2 {
+		t.Fatal(p3.Dir, j.compiled)
+	}
+	// 编译失败时不缓存
+	j.compileErr = &judge.CompileError{Msg: "error"}
+	if _, err := c.Get(context.Background(), &queue.Program{Language: "python", Code: "print(3)\n"}); err == nil {
+		t.Fatal("expected compile error")
+	}
+	if _, err := c.Get(context.Background(), &queue.Program{Language: "unknown"}); err == nil {
+		t.Fatal("expected unknown language")
+	}
+}
+
+func TestQueue(t *testing.T) {
+	q := &queue.Queue{RDB: rdb}
+	job := &queue.Job{
+		Kind:      queue.KindSubmit,
+		Identity:  "queue-test",
+		Language:  "go",
+		Code:      "package main\n",
+		Limit:     judge.Limit{MaxRuntime: 1000, MaxMem: 1024},
+		TestCases: []*queue.TestCase{{Identity: "1", Input: "1\n", Output: "1\n"}},
+	}
+	run := &queue.Job{Kind: queue.KindRun, Identity: "queue-test-run", Language: "go"}
+	if err := q.Push(ctx, job); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Push(ctx, run); err != nil {
+		t.Fatal(err)
+	}
+	// 自定义输入运行的任务优先，不支持的编程语言的任务不会被取出
+	worker := "queue-test-worker"
+	got, err := q.Pop(ctx, worker, []string{"python"}, time.Second)
+	if err != nil || got != nil {
+		t.Fatal(got, err)
+	}
+	got, err = q.Pop(ctx, worker, []string{"go"}, time.Second)
+	if err != nil || got.Identity != run.Identity {
+		t.Fatal(got, err)
+	}
+	if err := q.Ack(ctx, got); err != nil {
+		t.Fatal(err)
+	}
+	got, err = q.Pop(ctx, worker, []string{"go"}, time.Second)
+	if err != nil || got.Identity != job.Identity || got.TestCases[0].Output != "1\n" {
+		t.Fatal(got, err)
+	}
+	// 没有心跳的判题节点取出的任务被放回队列
+	if n, err := q.Requeue(ctx); err != nil || n != 1 {
+		t.Fatal(n, err)
+	}
+	got, err = q.Pop(ctx, worker, []string{"go"}, time.Second)
+	if err != nil || got == nil || got.Identity != job.Identity {
+		t.Fatal(got, err)
+	}
+	if err := q.Ack(ctx, got); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := q.Requeue(ctx); err != nil || n != 0 {
+		t.Fatal(n, err)
+	}
+
+	if err := q.PushRunResult(ctx, run.Identity, &judge.CaseResult{Status: judge.StatusAccepted, Stdout: "1\n"}); err != nil {
+		t.Fatal(err)
+	}
+	cr, err := q.WaitRunResult(ctx, run.Identity, time.Second)
+	if err != nil || cr.Stdout != "1\n" {
+		t.Fatal(cr, err)
+	}
+
+	w := &queue.WorkerInfo{ID: worker, Capacity: 1, Languages: []string{"go"}}
+	if err := q.Heartbeat(ctx, w); err != nil {
+		t.Fatal(err)
+	}
+	workers, err := q.Workers(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, v := range workers {
+		found = found || v.ID == w.ID
+	}
+	if !found {
+		t.Fatal("worker not found")
+	}
+	q.RemoveWorker(ctx, w.ID)
+}
+
+func TestWorkerSetupError(t *testing.T) {
+	q := &queue.Queue{RDB: rdb}
+	job := &queue.Job{
+		Kind:      queue.KindSubmit,
+		Identity:  "worker-setup-test",
+		Language:  "go",
+		Code:      "package main\n",
+		Limit:     judge.Limit{MaxRuntime: 1000, MaxMem: 1024},
+		TestCases: []*queue.TestCase{{Identity: "1", Input: "1\n", Output: "1\n"}},
+	}
+	if err := q.Push(ctx, job); err != nil {
+		t.Fatal(err)
+	}
+	// 临时目录不存在，代码写入失败
+	t.Setenv("TMPDIR", filepath.Join(t.TempDir(), "missing"))
+	w := worker.New(q, new(fakeJudge), 1, []string{"go"})
+	w.SetID("worker-setup-test-worker")
+	runCtx, cancel := context.WithCancel(ctx)
+	stopped := make(chan struct{})
+	go func() {
+		w.Run(runCtx)
+		close(stopped)
+	}()
+	res, err := q.PopResult(ctx, time.Second*5)
+	cancel()
+	<-stopped
+	if err != nil || res == nil || res.Identity != job.Identity || res.Result.Status != judge.StatusSystemError {
+		t.Fatal(res, err)
+	}
+	// 返回结果后任务已经确认，判题节点退出后不会被放回队列
+	if n, err := q.Requeue(ctx); err != nil || n != 0 {
+		t.Fatal(n, err)
+	}
+}
@@ -0,0 +1,61 @@
+package worker
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"gin_gorm_oj/judge"
+	"gin_gorm_oj/queue"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// 编译成功后写入的标记文件
+const compiledMark = ".compiled"
+
+// ProgramCache 评测程序和交互程序编译后的缓存，按照编程语言和代码的sha256保存，代码不变时只编译一次
+type ProgramCache struct {
+	Judge judge.Judge
+	Dir   string
+	mu    sync.Mutex
+}
+
+// Get 返回编译后的程序，p为nil时返回nil
+func (c *ProgramCache) Get(ctx context.Context, p *queue.Program) (*judge.Program, error) {
+	if p == nil {
+		return nil, nil
+	}
+	lang, ok := judge.GetLanguage(p.Language)
+	if !ok {
+		return nil, errors.New("不支持的编程语言:" + p.Language)
+	}
+	sum := sha256.Sum256([]byte(lang.Name + "\x00" + p.Code))
+	dir := filepath.Join(c.Dir, hex.EncodeToString(sum[:]))
+	prog := &judge.Program{Dir: dir, Language: lang}
+	if _, err := os.Stat(filepath.Join(dir, compiledMark)); err == nil {
+		return prog, nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, err := os.Stat(filepath.Join(dir, compiledMark)); err == nil {
+		return prog, nil
+	}
+	os.RemoveAll(dir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, lang.SourceFile)
+	if err := os.WriteFile(path, []byte(p.Code), 0644); err != nil {
+		return nil, err
+	}
+	if _, err := c.Judge.Compile(ctx, lang, path); err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, compiledMark), nil, 0644); err != nil {
+		return nil, err
+	}
+	return prog, nil
+}
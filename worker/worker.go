@@ -0,0 +1,277 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gin_gorm_oj/define"
+	"gin_gorm_oj/judge"
+	"gin_gorm_oj/queue"
+	"gin_gorm_oj/storage"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Worker 判题节点，从队列中取出支持的编程语言的任务进行判断并返回结果，不访问数据库
+// 可以在判题服务中运行，也可以通过cmd/judge-worker在其他机器上运行
+type Worker struct {
+	Queue *queue.Queue
+	Judge judge.Judge
+	// Programs 评测程序和交互程序编译后的缓存
+	Programs *ProgramCache
+	info     queue.WorkerInfo
+	busy     int32
+}
+
+// New 创建判题节点，capacity为同时处理的任务数
+func New(q *queue.Queue, j judge.Judge, capacity int, languages []string) *Worker {
+	host, _ := os.Hostname()
+	return &Worker{
+		Queue:    q,
+		Judge:    j,
+		Programs: &ProgramCache{Judge: j, Dir: define.JudgeProgramCacheDir},
+		info: queue.WorkerInfo{
+			ID:        fmt.Sprintf("%s-%d", host, os.Getpid()),
+			Host:      host,
+			Capacity:  capacity,
+			Languages: languages,
+		},
+	}
+}
+
+// SetID 设置判题节点的标识，默认为主机名-进程号
+func (w *Worker) SetID(id string) {
+	w.info.ID = id
+}
+
+// AvailableLanguages 本机安装了编译器或解释器的编程语言
+func AvailableLanguages() []string {
+	res := make([]string, 0, len(judge.Languages))
+	for name, lang := range judge.Languages {
+		cmd := lang.Run
+		if len(lang.Compile) > 0 {
+			cmd = lang.Compile
+		}
+		if _, err := exec.LookPath(cmd[0]); err == nil {
+			res = append(res, name)
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+// Run 处理任务直到ctx被取消，取消后等待已经取出的任务完成
+func (w *Worker) Run(ctx context.Context) {
+	w.info.Started = time.Now()
+	// 以相同的标识重新启动时，上次取出但没有完成的任务在心跳过期前不会被放回队列
+	if n, err := w.Queue.RequeueWorker(context.Background(), w.info.ID); err != nil {
+		log.Println("requeue jobs err:", err)
+	} else if n > 0 {
+		log.Printf("judge worker %s requeued %d unfinished jobs", w.info.ID, n)
+	}
+	log.Printf("judge worker %s started, capacity %d, languages %v", w.info.ID, w.info.Capacity, w.info.Languages)
+	// 取出任务前先发送心跳，否则刚取出的任务会被判题服务当作已下线节点的任务放回队列
+	w.heartbeat()
+	var wg sync.WaitGroup
+	for i := 0; i < w.info.Capacity; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.loop(ctx)
+		}()
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	ticker := time.NewTicker(queue.HeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			w.heartbeat()
+		case <-done:
+			w.Queue.RemoveWorker(context.Background(), w.info.ID)
+			log.Printf("judge worker %s stopped", w.info.ID)
+			return
+		}
+	}
+}
+
+func (w *Worker) heartbeat() {
+	info := w.info
+	info.Busy = int(atomic.LoadInt32(&w.busy))
+	if err := w.Queue.Heartbeat(context.Background(), &info); err != nil {
+		log.Println("heartbeat err:", err)
+	}
+}
+
+func (w *Worker) loop(ctx context.Context) {
+	for ctx.Err() == nil {
+		job, err := w.Queue.Pop(ctx, w.info.ID, w.info.Languages, time.Second*5)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Println("pop job err:", err)
+				time.Sleep(time.Second)
+			}
+			continue
+		}
+		if job == nil {
+			continue
+		}
+		atomic.AddInt32(&w.busy, 1)
+		err = w.handle(context.Background(), job)
+		atomic.AddInt32(&w.busy, -1)
+		// 结果没有返回时不确认任务，任务留在处理中列表，由判题服务放回队列
+		if err != nil {
+			log.Println("reply job err:", job.Kind, job.Identity, err)
+			continue
+		}
+		if err := w.Queue.Ack(context.Background(), job); err != nil {
+			log.Println("ack job err:", job.Kind, job.Identity, err)
+		}
+	}
+}
+
+// handle 处理一个任务并返回结果，处理出错时返回系统错误的结果，只在结果没有返回时返回错误
+func (w *Worker) handle(ctx context.Context, job *queue.Job) error {
+	lang, ok := judge.GetLanguage(job.Language)
+	if !ok {
+		return w.reply(ctx, job, &judge.Result{Status: judge.StatusSystemError, Msg: "系统错误:不支持的编程语言" + job.Language})
+	}
+	if job.Kind == queue.KindCompile {
+		return w.Queue.PushRunResult(ctx, job.Identity, w.compile(ctx, job))
+	}
+	path, err := writeCode(lang, job.Code)
+	if err != nil {
+		log.Println("write code err:", job.Identity, err)
+		return w.reply(ctx, job, &judge.Result{Status: judge.StatusSystemError, Msg: "系统错误:代码写入失败"})
+	}
+	defer os.RemoveAll(filepath.Dir(path))
+	if job.Kind == queue.KindRun {
+		res := judge.Execute(ctx, w.Judge, path, job.Language, job.Input, job.Limit)
+		return w.Queue.PushRunResult(ctx, job.Identity, res)
+	}
+	return w.reply(ctx, job, w.judge(ctx, job, path))
+}
+
+// writeCode 将代码写入临时目录，返回代码文件的路径，隔离环境中用户程序以nobody运行，目录需要可以读取
+func writeCode(lang *judge.Language, code string) (string, error) {
+	dir, err := os.MkdirTemp("", "judge-code-")
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, lang.SourceFile)
+	if err := os.Chmod(dir, 0755); err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+	if err := os.WriteFile(path, []byte(code), 0644); err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+	return path, nil
+}
+
+// compile 编译检查评测程序或交互程序，编译失败时返回编译器的输出
+func (w *Worker) compile(ctx context.Context, job *queue.Job) *judge.CaseResult {
+	_, err := w.Programs.Get(ctx, &queue.Program{Language: job.Language, Code: job.Code})
+	if err == nil {
+		return &judge.CaseResult{Status: judge.StatusAccepted}
+	}
+	var ce *judge.CompileError
+	if errors.As(err, &ce) {
+		return &judge.CaseResult{Status: judge.StatusCompileError, Msg: ce.Msg}
+	}
+	log.Println("compile program err:", job.Identity, err)
+	return &judge.CaseResult{Status: judge.StatusSystemError, Msg: "系统错误"}
+}
+
+// judge 判断提交，评测程序编译失败或测试数据读取失败时为系统错误
+func (w *Worker) judge(ctx context.Context, job *queue.Job, path string) *judge.Result {
+	cmp := job.Compare
+	var err error
+	if cmp.Checker, err = w.Programs.Get(ctx, job.Checker); err != nil {
+		log.Println("compile checker err:", job.Identity, err)
+		return &judge.Result{Status: judge.StatusSystemError, Msg: "系统错误:评测程序编译失败"}
+	}
+	if cmp.Interactor, err = w.Programs.Get(ctx, job.Interactor); err != nil {
+		log.Println("compile interactor err:", job.Identity, err)
+		return &judge.Result{Status: judge.StatusSystemError, Msg: "系统错误:交互程序编译失败"}
+	}
+	total := len(job.TestCases)
+	w.publish(ctx, job.Identity, &queue.Event{Type: queue.EventJudging, Total: total})
+	// 测试数据从存储下载到本节点的缓存中
+	tcs := make([]*judge.TestCase, 0, len(job.TestCases))
+	for _, tc := range job.TestCases {
+		jtc, err := testCase(ctx, tc)
+		if err != nil {
+			log.Println("get test case data err:", tc.Identity, err)
+			return &judge.Result{Status: judge.StatusSystemError, Msg: "系统错误:测试数据读取失败"}
+		}
+		tcs = append(tcs, jtc)
+	}
+	parallel := define.JudgeCaseParallel
+	if parallel <= 0 {
+		parallel = runtime.NumCPU()
+	}
+	done := 0
+	return judge.Do(ctx, w.Judge, &judge.Submission{
+		Path:          path,
+		Language:      job.Language,
+		Limit:         job.Limit,
+		Compare:       cmp,
+		TestCases:     tcs,
+		StopOnFailure: job.StopOnFailure,
+		Parallel:      parallel,
+		Progress: func(i int, c *judge.CaseResult) {
+			done++
+			// 进度公开推送，不包含可能输出了隐藏测试数据的标准错误
+			pc := *c
+			pc.Stderr = ""
+			w.publish(ctx, job.Identity, &queue.Event{Type: queue.EventCase, Total: total, Done: done, Index: i, Case: &pc})
+		},
+	})
+}
+
+// publish 发布判断进度，失败时不影响判断
+func (w *Worker) publish(ctx context.Context, identity string, e *queue.Event) {
+	if err := w.Queue.Publish(ctx, identity, e); err != nil {
+		log.Println("publish progress err:", identity, err)
+	}
+}
+
+// reply 返回提交的判断结果
+func (w *Worker) reply(ctx context.Context, job *queue.Job, res *judge.Result) error {
+	if job.Kind == queue.KindRun || job.Kind == queue.KindCompile {
+		return w.Queue.PushRunResult(ctx, job.Identity, &judge.CaseResult{Status: res.Status, Msg: res.Msg})
+	}
+	return w.Queue.PushResult(ctx, &queue.Result{Identity: job.Identity, Worker: w.info.ID, Result: res})
+}
+
+// testCase 判题使用的测试用例，数据保存在存储中时通过本节点的缓存读取
+func testCase(ctx context.Context, tc *queue.TestCase) (*judge.TestCase, error) {
+	res := &judge.TestCase{Identity: tc.Identity}
+	if tc.InputKey == "" {
+		res.Input, res.Output = tc.Input, tc.Output
+		return res, nil
+	}
+	var err error
+	res.InputFile, err = storage.DefaultCache.File(ctx, tc.InputKey, tc.InputSha256)
+	if err != nil {
+		return nil, err
+	}
+	res.OutputFile, err = storage.DefaultCache.File(ctx, tc.OutputKey, tc.OutputSha256)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}